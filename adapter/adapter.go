@@ -0,0 +1,160 @@
+// Package adapter puts every cache benchmarked in this repository behind a
+// single Cache interface and registers it by name, so benchmarks and tests
+// can be written once and run against any subset of implementations.
+//
+// The gocache versions are registered as "v1" through "v12", v12 being the
+// one bounded by ARC, v9 bounded by W-TinyLFU instead of random eviction as
+// "v9-tinylfu", and the generic variant of v9 as "v9-typed"; the third-party
+// libraries as "go-cache", "freecache", "ristretto" and "bigcache".
+// Config.Policy swaps the eviction policy of v5, v9 and v11.
+package adapter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"benchmark-gocache/policy"
+)
+
+// DefaultTTL is the default expiration handed to implementations when
+// Config.TTL is not set. It matches the TTL the original benchmarks used.
+const DefaultTTL = 10 * time.Minute
+
+// Cache is the common surface every implementation is adapted to.
+//
+// Values are byte slices so the byte-oriented libraries (freecache, bigcache)
+// and the interface{}-based gocache versions can be driven by the same workload.
+type Cache interface {
+	// Set stores value under key. A ttl <= 0 stores the entry without expiration.
+	Set(key string, value []byte, ttl time.Duration)
+
+	// Get returns the value stored under key and whether it was found.
+	Get(key string) ([]byte, bool)
+
+	// Delete removes key from the cache. Missing keys are ignored.
+	Delete(key string)
+
+	// Len returns the number of stored entries, or -1 if the
+	// implementation cannot report it.
+	Len() int
+
+	// Close releases the resources held by the cache.
+	Close() error
+}
+
+// Waiter is implemented by caches whose Set is applied asynchronously
+// (ristretto). Wait blocks until all pending writes are visible to Get.
+type Waiter interface {
+	Wait()
+}
+
+// Wait calls c.Wait if c implements Waiter and is a no-op otherwise.
+func Wait(c Cache) {
+	if w, ok := c.(Waiter); ok {
+		w.Wait()
+	}
+}
+
+// Config holds the options shared by every factory. Implementations ignore
+// the fields they have no equivalent for.
+type Config struct {
+	// TTL is the default expiration of the cache. Zero means DefaultTTL.
+	TTL time.Duration
+
+	// CleanupInterval is the janitor interval for implementations that take
+	// one separately from the TTL (v2, v3, v8, go-cache). Zero keeps the
+	// implementation's own default.
+	CleanupInterval time.Duration
+
+	// MaxEntries bounds the cache to about this many entries. Zero means unbounded.
+	MaxEntries int
+
+	// MaxBytes bounds the memory used by keys and values. Zero means unbounded.
+	MaxBytes int64
+
+	// Policy chooses the entries evicted to stay within MaxEntries and
+	// MaxBytes. Only the versions with SetEvictionPolicy (v5, v9 and v11)
+	// accept one, and only together with MaxEntries. Nil keeps the
+	// implementation's own eviction.
+	Policy policy.Factory
+}
+
+// ErrBudgetUnsupported is returned by New when the implementation cannot
+// honour the MaxEntries or MaxBytes budget, or the eviction Policy,
+// requested in Config.
+var ErrBudgetUnsupported = errors.New("adapter: budget not supported by this cache")
+
+// Factory builds a new, empty cache from cfg.
+type Factory func(cfg Config) (Cache, error)
+
+var (
+	mu        sync.RWMutex
+	factories = make(map[string]Factory)
+	names     []string
+)
+
+// Register makes a cache implementation available under name.
+// It panics if name is empty, f is nil or name is already registered.
+func Register(name string, f Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if name == "" || f == nil {
+		panic("adapter: Register with empty name or nil factory")
+	}
+	if _, dup := factories[name]; dup {
+		panic("adapter: Register called twice for " + name)
+	}
+	factories[name] = f
+	names = append(names, name)
+}
+
+// Names returns the registered implementation names in registration order.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return append([]string(nil), names...)
+}
+
+// New builds the implementation registered under name.
+func New(name string, cfg Config) (Cache, error) {
+	mu.RLock()
+	f, ok := factories[name]
+	mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("adapter: unknown cache %q", name)
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = DefaultTTL
+	}
+	return f(cfg)
+}
+
+// Select parses a comma-separated list of implementation names, as passed on
+// the command line, and validates it against the registry.
+// An empty list selects every registered implementation.
+func Select(list string) ([]string, error) {
+	if strings.TrimSpace(list) == "" {
+		return Names(), nil
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+
+	var selected []string
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := factories[name]; !ok {
+			return nil, fmt.Errorf("adapter: unknown cache %q", name)
+		}
+		selected = append(selected, name)
+	}
+	return selected, nil
+}
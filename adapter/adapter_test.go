@@ -0,0 +1,221 @@
+package adapter
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"benchmark-gocache/policy"
+)
+
+var caches = flag.String("caches", "", "comma-separated cache names to test (default: all registered)")
+
+// forEachCache runs fn as a subtest against a fresh instance of every selected
+// cache built with cfg. Caches that cannot honour the budget in cfg are skipped.
+func forEachCache(t *testing.T, cfg Config, fn func(t *testing.T, c Cache)) {
+	selected, err := Select(*caches)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range selected {
+		t.Run(name, func(t *testing.T) {
+			c, err := New(name, cfg)
+			if errors.Is(err, ErrBudgetUnsupported) {
+				t.Skip(err)
+			}
+			if err != nil {
+				t.Fatalf("New(%q) error: %v", name, err)
+			}
+			defer c.Close()
+			fn(t, c)
+		})
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	want := []string{
+		"v1", "v2", "v3", "v4", "v5", "v6", "v7", "v8", "v9", "v10", "v11",
+		"v12", "v9-tinylfu", "v9-typed", "go-cache", "freecache", "ristretto", "bigcache",
+	}
+	got := Names()
+	if len(got) != len(want) {
+		t.Fatalf("Names() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Names()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	if _, err := New("nope", Config{}); err == nil {
+		t.Error("New with unknown name should fail")
+	}
+}
+
+func TestSelect(t *testing.T) {
+	got, err := Select(" v9, v11 ,,go-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[0] != "v9" || got[1] != "v11" || got[2] != "go-cache" {
+		t.Errorf("Select() = %v", got)
+	}
+
+	if _, err := Select("v9,v99"); err == nil {
+		t.Error("Select with unknown name should fail")
+	}
+
+	all, err := Select("")
+	if err != nil || len(all) != len(Names()) {
+		t.Errorf("Select(\"\") = %v, %v, want all names", all, err)
+	}
+}
+
+func TestCache_SetGet(t *testing.T) {
+	forEachCache(t, Config{}, func(t *testing.T, c Cache) {
+		c.Set("key1", []byte("value1"), time.Minute)
+		c.Set("key2", []byte("value2"), 0)
+		Wait(c)
+
+		if got, ok := c.Get("key1"); !ok || !bytes.Equal(got, []byte("value1")) {
+			t.Errorf("Get(key1) = %q, %v, want value1, true", got, ok)
+		}
+		if got, ok := c.Get("key2"); !ok || !bytes.Equal(got, []byte("value2")) {
+			t.Errorf("Get(key2) = %q, %v, want value2, true", got, ok)
+		}
+		if _, ok := c.Get("missing"); ok {
+			t.Error("Get(missing) found a value")
+		}
+	})
+}
+
+func TestCache_Overwrite(t *testing.T) {
+	forEachCache(t, Config{}, func(t *testing.T, c Cache) {
+		c.Set("key", []byte("old"), time.Minute)
+		Wait(c)
+		c.Set("key", []byte("new"), time.Minute)
+		Wait(c)
+
+		if got, ok := c.Get("key"); !ok || !bytes.Equal(got, []byte("new")) {
+			t.Errorf("Get(key) = %q, %v, want new, true", got, ok)
+		}
+	})
+}
+
+func TestCache_Delete(t *testing.T) {
+	forEachCache(t, Config{}, func(t *testing.T, c Cache) {
+		c.Set("key", []byte("value"), time.Minute)
+		Wait(c)
+		c.Delete("key")
+		c.Delete("missing")
+		Wait(c)
+
+		if _, ok := c.Get("key"); ok {
+			t.Error("Get after Delete found a value")
+		}
+	})
+}
+
+func TestCache_Len(t *testing.T) {
+	forEachCache(t, Config{}, func(t *testing.T, c Cache) {
+		const n = 100
+		for i := 0; i < n; i++ {
+			c.Set(strconv.Itoa(i), []byte("v"), time.Minute)
+		}
+		Wait(c)
+
+		if got := c.Len(); got != n && got != -1 {
+			t.Errorf("Len() = %d, want %d", got, n)
+		}
+	})
+}
+
+func TestCache_MaxEntries(t *testing.T) {
+	const max = 1000
+	forEachCache(t, Config{MaxEntries: max}, func(t *testing.T, c Cache) {
+		for i := 0; i < 10*max; i++ {
+			c.Set(strconv.Itoa(i), []byte("v"), time.Minute)
+		}
+		Wait(c)
+
+		// Sharded versions split the budget per shard, so allow a little slack.
+		if got := c.Len(); got > max+max/10 {
+			t.Errorf("Len() = %d, want about %d", got, max)
+		}
+	})
+}
+
+func TestCache_Policy(t *testing.T) {
+	const max = 1000
+	cfg := Config{MaxEntries: max, Policy: policy.NewSIEVE}
+	for _, name := range []string{"v5", "v9", "v11"} {
+		c, err := New(name, cfg)
+		if err != nil {
+			t.Fatalf("New(%q) with a policy error: %v", name, err)
+		}
+		c.Close()
+	}
+	forEachCache(t, cfg, func(t *testing.T, c Cache) {
+		for i := 0; i < 10*max; i++ {
+			c.Set(strconv.Itoa(i), []byte("v"), time.Minute)
+		}
+		if got := c.Len(); got > max+max/10 {
+			t.Errorf("Len() = %d, want about %d", got, max)
+		}
+	})
+}
+
+func TestCache_MaxBytes(t *testing.T) {
+	const max = 4 << 20
+	forEachCache(t, Config{MaxBytes: max}, func(t *testing.T, c Cache) {
+		val := make([]byte, 1024)
+		for i := 0; i < 4*max/len(val); i++ {
+			c.Set(strconv.Itoa(i), val, time.Minute)
+		}
+		Wait(c)
+
+		if got := c.Len(); got > max/len(val) {
+			t.Errorf("Len() = %d, want at most %d", got, max/len(val))
+		}
+	})
+}
+
+// TestNew_NoLeaks checks that building every cache, with budgets it may
+// reject or with a janitor interval, and closing the ones built leaves no
+// goroutine behind. go-cache only stops its janitor from a finalizer, so the
+// test collects garbage while it waits.
+func TestNew_NoLeaks(t *testing.T) {
+	selected, err := Select(*caches)
+	if err != nil {
+		t.Fatal(err)
+	}
+	configs := []Config{
+		{MaxBytes: 1 << 20},
+		{MaxEntries: 1000, Policy: policy.NewLRU},
+		{Policy: policy.NewLRU},
+		{CleanupInterval: time.Minute},
+	}
+	before := runtime.NumGoroutine()
+	for _, name := range selected {
+		for _, cfg := range configs {
+			c, err := New(name, cfg)
+			if errors.Is(err, ErrBudgetUnsupported) {
+				continue
+			}
+			if err != nil {
+				t.Fatalf("New(%q) error: %v", name, err)
+			}
+			c.Close()
+		}
+	}
+	for deadline := time.Now().Add(time.Second); runtime.NumGoroutine() > before; time.Sleep(time.Millisecond) {
+		runtime.GC()
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines remain after building every cache, want at most %d", runtime.NumGoroutine(), before)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package adapter
+
+import (
+	"time"
+
+	"benchmark-gocache/policy"
+	v1 "benchmark-gocache/v1"
+	v10 "benchmark-gocache/v10"
+	v11 "benchmark-gocache/v11"
+	v12 "benchmark-gocache/v12"
+	v2 "benchmark-gocache/v2"
+	v3 "benchmark-gocache/v3"
+	v4 "benchmark-gocache/v4"
+	v5 "benchmark-gocache/v5"
+	v6 "benchmark-gocache/v6"
+	v7 "benchmark-gocache/v7"
+	v8 "benchmark-gocache/v8"
+	v9 "benchmark-gocache/v9"
+)
+
+// v8Shards is the shard count the original benchmarks used for v8.
+const v8Shards = 8
+
+func init() {
+	Register("v1", func(cfg Config) (Cache, error) {
+		return newVersion(cfg, &versionCache{s: v1.New(cfg.TTL), noExp: v1.NoExpiration})
+	})
+	Register("v2", func(cfg Config) (Cache, error) {
+		if cfg.MaxBytes > 0 || cfg.Policy != nil {
+			return nil, ErrBudgetUnsupported
+		}
+		c := v2.New[string, []byte](cfg.TTL, cfg.CleanupInterval)
+		c.SetMaxEntries(cfg.MaxEntries)
+		return &v2Cache{c: c}, nil
+	})
+	Register("v3", func(cfg Config) (Cache, error) {
+		c := v3.New(cfg.TTL, cfg.CleanupInterval)
+		return newVersion(cfg, &versionCache{s: c, noExp: v3.NoExpiration})
+	})
+	Register("v4", func(cfg Config) (Cache, error) {
+		return newVersion(cfg, &versionCache{s: v4.New(cfg.TTL), noExp: v4.NoExpiration})
+	})
+	Register("v5", func(cfg Config) (Cache, error) {
+		return newVersion(cfg, &versionCache{s: v5.New(cfg.TTL), noExp: v5.NoExpiration})
+	})
+	Register("v6", func(cfg Config) (Cache, error) {
+		return newVersion(cfg, &versionCache{s: v6.New(cfg.TTL), noExp: v6.NoExpiration})
+	})
+	Register("v7", func(cfg Config) (Cache, error) {
+		c := v7.New(cfg.TTL)
+		if cfg.CleanupInterval > 0 {
+			c.SetSweeper(cfg.CleanupInterval, 0)
+		}
+		return newVersion(cfg, &versionCache{s: c, noExp: v7.NoExpiration})
+	})
+	Register("v8", func(cfg Config) (Cache, error) {
+		var c *v8.Cache
+		if cfg.CleanupInterval > 0 {
+			c = v8.New(cfg.TTL, v8Shards, cfg.CleanupInterval)
+		} else {
+			c = v8.New(cfg.TTL, v8Shards)
+		}
+		return newVersion(cfg, &versionCache{s: c, noExp: v8.NoExpiration})
+	})
+	Register("v9", func(cfg Config) (Cache, error) {
+		return newVersion(cfg, &versionCache{s: v9.New(cfg.TTL), noExp: v9.NoExpiration})
+	})
+	Register("v10", func(cfg Config) (Cache, error) {
+		return newVersion(cfg, &versionCache{s: v10.New(cfg.TTL), noExp: v10.NoExpiration})
+	})
+	Register("v11", func(cfg Config) (Cache, error) {
+		return newVersion(cfg, &versionCache{s: v11.New(cfg.TTL), noExp: v11.NoExpiration})
+	})
+	Register("v12", func(cfg Config) (Cache, error) {
+		return newVersion(cfg, &versionCache{s: v12.New(cfg.TTL), noExp: v12.NoExpiration})
+	})
+	Register("v9-tinylfu", func(cfg Config) (Cache, error) {
+		if cfg.MaxBytes > 0 || cfg.Policy != nil {
+			return nil, ErrBudgetUnsupported
+		}
+		c := v9.New(cfg.TTL)
+		c.SetTinyLFU(cfg.MaxEntries)
+		return &versionCache{s: c, noExp: v9.NoExpiration}, nil
+	})
+	Register("v9-typed", func(cfg Config) (Cache, error) {
+		if cfg.MaxBytes > 0 || cfg.Policy != nil {
+			return nil, ErrBudgetUnsupported
+		}
+		c := v9.NewTyped[string, []byte](v9.HashString, cfg.TTL)
+		c.SetMaxEntries(cfg.MaxEntries)
+		return &v9TypedCache{c: c}, nil
+	})
+}
+
+// store is the method set shared by the interface{}-based gocache versions.
+type store interface {
+	Set(key string, value any, ttl time.Duration)
+	Get(key string) (any, bool)
+	Delete(key string)
+	Len() int
+	SetMaxEntries(n int)
+	Close() error
+}
+
+// costStore is implemented by the versions that can bound the bytes they
+// hold (v9, v10 and v11). Their DefaultSizer charges each entry its key and
+// value lengths.
+type costStore interface {
+	SetMaxCost(n int64)
+}
+
+// policyStore is implemented by the versions that take an eviction policy
+// (v5, v9 and v11).
+type policyStore interface {
+	SetEvictionPolicy(n int, newPolicy policy.Factory)
+}
+
+// newVersion applies the budget in cfg to c. Every gocache version supports an
+// entry budget; only those implementing costStore support a byte budget, and
+// only those implementing policyStore an eviction policy. A rejected c is
+// closed, since it may already run a janitor the caller could not stop.
+func newVersion(cfg Config, c *versionCache) (Cache, error) {
+	cs, costOK := c.s.(costStore)
+	ps, policyOK := c.s.(policyStore)
+	if cfg.MaxBytes > 0 && !costOK || cfg.Policy != nil && (!policyOK || cfg.MaxEntries <= 0) {
+		c.s.Close()
+		return nil, ErrBudgetUnsupported
+	}
+	if cfg.MaxBytes > 0 {
+		cs.SetMaxCost(cfg.MaxBytes)
+	}
+	if cfg.Policy == nil {
+		c.s.SetMaxEntries(cfg.MaxEntries)
+	} else {
+		ps.SetEvictionPolicy(cfg.MaxEntries, cfg.Policy)
+	}
+	return c, nil
+}
+
+// versionCache adapts a gocache version to Cache.
+type versionCache struct {
+	s     store
+	noExp time.Duration // the version's "never expire" ttl
+}
+
+func (c *versionCache) Set(key string, value []byte, ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = c.noExp
+	}
+	c.s.Set(key, value, ttl)
+}
+
+func (c *versionCache) Get(key string) ([]byte, bool) {
+	v, ok := c.s.Get(key)
+	if !ok {
+		return nil, false
+	}
+	b, _ := v.([]byte)
+	return b, true
+}
+
+func (c *versionCache) Delete(key string) { c.s.Delete(key) }
+
+func (c *versionCache) Len() int { return c.s.Len() }
+
+func (c *versionCache) Close() error { return c.s.Close() }
+
+// v2Cache adapts the generic v2 cache, whose Set refuses existing keys and
+// whose methods report misses as errors.
+type v2Cache struct {
+	c *v2.Cache[string, []byte]
+}
+
+func (c *v2Cache) Set(key string, value []byte, ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = v2.NoExpiration
+	}
+	if err := c.c.Set(key, value, ttl); err != nil {
+		c.c.Update(key, value, ttl)
+	}
+}
+
+func (c *v2Cache) Get(key string) ([]byte, bool) {
+	item, err := c.c.Get(key)
+	if err != nil {
+		return nil, false
+	}
+	return item.Value(), true
+}
+
+func (c *v2Cache) Delete(key string) { c.c.Delete(key) }
+
+func (c *v2Cache) Len() int { return c.c.Count() }
+
+func (c *v2Cache) Close() error { return c.c.Close() }
+
+// v9TypedCache adapts the generic variant of v9, which stores []byte values
+// without boxing them.
+type v9TypedCache struct {
+	c *v9.TypedCache[string, []byte]
+}
+
+func (c *v9TypedCache) Set(key string, value []byte, ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = v9.NoExpiration
+	}
+	c.c.Set(key, value, ttl)
+}
+
+func (c *v9TypedCache) Get(key string) ([]byte, bool) { return c.c.Get(key) }
+
+func (c *v9TypedCache) Delete(key string) { c.c.Delete(key) }
+
+func (c *v9TypedCache) Len() int { return c.c.Len() }
+
+func (c *v9TypedCache) Close() error { return c.c.Close() }
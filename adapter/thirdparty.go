@@ -0,0 +1,224 @@
+package adapter
+
+import (
+	"time"
+
+	bigcache "github.com/allegro/bigcache"
+	freecache "github.com/coocood/freecache"
+	ristretto "github.com/dgraph-io/ristretto"
+	gocache "github.com/patrickmn/go-cache"
+)
+
+const (
+	// freecacheSize is the arena size given to freecache (100MB).
+	freecacheSize = 100 * 1024 * 1024
+
+	// ristretto sizing used by the original benchmarks.
+	ristrettoCounters = 1e7
+	ristrettoMaxCost  = 1 << 30
+	ristrettoBuffer   = 64
+
+	// ristrettoAvgEntry is the entry size assumed when sizing ristretto's
+	// frequency counters for a byte budget.
+	ristrettoAvgEntry = 64
+
+	// bigcacheMinShardEntries is the number of entries bigcache preallocates
+	// in every shard regardless of the configured window.
+	bigcacheMinShardEntries = 10
+)
+
+func init() {
+	Register("go-cache", func(cfg Config) (Cache, error) {
+		if cfg.MaxEntries > 0 || cfg.MaxBytes > 0 || cfg.Policy != nil {
+			return nil, ErrBudgetUnsupported
+		}
+		return &goCache{c: gocache.New(cfg.TTL, cfg.CleanupInterval)}, nil
+	})
+	Register("freecache", func(cfg Config) (Cache, error) {
+		if cfg.MaxEntries > 0 || cfg.Policy != nil {
+			return nil, ErrBudgetUnsupported
+		}
+		size := freecacheSize
+		if cfg.MaxBytes > 0 {
+			size = int(cfg.MaxBytes)
+		}
+		return &freeCache{c: freecache.NewCache(size)}, nil
+	})
+	Register("ristretto", newRistretto)
+	Register("bigcache", func(cfg Config) (Cache, error) {
+		if cfg.MaxEntries > 0 || cfg.Policy != nil {
+			return nil, ErrBudgetUnsupported
+		}
+		bc := bigcache.DefaultConfig(cfg.TTL)
+		bc.Verbose = false
+		if cfg.MaxBytes > 0 {
+			// bigcache takes its hard limit in whole megabytes, and only
+			// enforces it when the initial shard allocation fits under it.
+			// Every shard preallocates room for at least 10 entries, so
+			// small budgets also need fewer shards.
+			bc.HardMaxCacheSize = int((cfg.MaxBytes + 1<<20 - 1) >> 20)
+			bc.MaxEntriesInWindow = int(cfg.MaxBytes) / bc.MaxEntrySize
+			for bc.Shards > 1 && int64(bc.Shards*bigcacheMinShardEntries*bc.MaxEntrySize) > cfg.MaxBytes {
+				bc.Shards /= 2
+			}
+		}
+		c, err := bigcache.NewBigCache(bc)
+		if err != nil {
+			return nil, err
+		}
+		return &bigCache{c: c}, nil
+	})
+}
+
+// newRistretto sizes ristretto for the budget in cfg. With an entry budget
+// every entry costs 1; with a byte budget an entry costs its key and value
+// length. Either way the internal overhead is left out of the cost so the
+// budget means the same thing as for the other caches.
+func newRistretto(cfg Config) (Cache, error) {
+	if cfg.MaxEntries > 0 && cfg.MaxBytes > 0 || cfg.Policy != nil {
+		return nil, ErrBudgetUnsupported
+	}
+	rc := &ristretto.Config{
+		NumCounters: ristrettoCounters,
+		MaxCost:     ristrettoMaxCost,
+		BufferItems: ristrettoBuffer,
+	}
+	var costBytes bool
+	switch {
+	case cfg.MaxEntries > 0:
+		rc.NumCounters = int64(cfg.MaxEntries) * 10
+		rc.MaxCost = int64(cfg.MaxEntries)
+		rc.IgnoreInternalCost = true
+	case cfg.MaxBytes > 0:
+		rc.NumCounters = cfg.MaxBytes / ristrettoAvgEntry * 10
+		rc.MaxCost = cfg.MaxBytes
+		rc.IgnoreInternalCost = true
+		costBytes = true
+	}
+	c, err := ristretto.NewCache(rc)
+	if err != nil {
+		return nil, err
+	}
+	return &ristrettoCache{c: c, costBytes: costBytes}, nil
+}
+
+// goCache adapts patrickmn/go-cache.
+type goCache struct {
+	c *gocache.Cache
+}
+
+func (c *goCache) Set(key string, value []byte, ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = gocache.NoExpiration
+	}
+	c.c.Set(key, value, ttl)
+}
+
+func (c *goCache) Get(key string) ([]byte, bool) {
+	v, ok := c.c.Get(key)
+	if !ok {
+		return nil, false
+	}
+	b, _ := v.([]byte)
+	return b, true
+}
+
+func (c *goCache) Delete(key string) { c.c.Delete(key) }
+
+func (c *goCache) Len() int { return c.c.ItemCount() }
+
+// Close is a no-op. go-cache offers no way to stop the janitor it starts when
+// Config.CleanupInterval > 0: a finalizer stops it once the cache is garbage
+// collected, so the goroutine is known to outlive Close until the next GC.
+func (c *goCache) Close() error { return nil }
+
+// freeCache adapts coocood/freecache. Its TTL has one second resolution,
+// so positive TTLs are rounded up to a whole second.
+type freeCache struct {
+	c *freecache.Cache
+}
+
+func (c *freeCache) Set(key string, value []byte, ttl time.Duration) {
+	var secs int
+	if ttl > 0 {
+		secs = int((ttl + time.Second - 1) / time.Second)
+	}
+	c.c.Set([]byte(key), value, secs)
+}
+
+func (c *freeCache) Get(key string) ([]byte, bool) {
+	v, err := c.c.Get([]byte(key))
+	if err != nil {
+		return nil, false
+	}
+	return v, true
+}
+
+func (c *freeCache) Delete(key string) { c.c.Del([]byte(key)) }
+
+func (c *freeCache) Len() int { return int(c.c.EntryCount()) }
+
+func (c *freeCache) Close() error { return nil }
+
+// ristrettoCache adapts dgraph-io/ristretto. Len is not supported because
+// ristretto only counts keys when metrics are enabled.
+type ristrettoCache struct {
+	c         *ristretto.Cache
+	costBytes bool // cost entries by size instead of 1
+}
+
+func (c *ristrettoCache) Set(key string, value []byte, ttl time.Duration) {
+	if ttl < 0 {
+		ttl = 0
+	}
+	cost := int64(1)
+	if c.costBytes {
+		cost = int64(len(key) + len(value))
+	}
+	c.c.SetWithTTL(key, value, cost, ttl)
+}
+
+func (c *ristrettoCache) Get(key string) ([]byte, bool) {
+	v, ok := c.c.Get(key)
+	if !ok {
+		return nil, false
+	}
+	b, _ := v.([]byte)
+	return b, true
+}
+
+func (c *ristrettoCache) Delete(key string) { c.c.Del(key) }
+
+func (c *ristrettoCache) Len() int { return -1 }
+
+func (c *ristrettoCache) Close() error {
+	c.c.Close()
+	return nil
+}
+
+// Wait blocks until buffered Sets have been applied.
+func (c *ristrettoCache) Wait() { c.c.Wait() }
+
+// bigCache adapts allegro/bigcache. bigcache has a single life window for
+// the whole cache, so the per-entry ttl passed to Set is ignored.
+type bigCache struct {
+	c *bigcache.BigCache
+}
+
+func (c *bigCache) Set(key string, value []byte, _ time.Duration) {
+	c.c.Set(key, value)
+}
+
+func (c *bigCache) Get(key string) ([]byte, bool) {
+	v, err := c.c.Get(key)
+	if err != nil {
+		return nil, false
+	}
+	return v, true
+}
+
+func (c *bigCache) Delete(key string) { c.c.Delete(key) }
+
+func (c *bigCache) Len() int { return c.c.Len() }
+
+func (c *bigCache) Close() error { return c.c.Close() }
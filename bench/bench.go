@@ -0,0 +1,188 @@
+// Package bench runs timed workloads against the registered caches outside
+// of go test, so runs can be configured from the command line and their
+// results written out as JSON, CSV or Markdown.
+package bench
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"benchmark-gocache/adapter"
+	"benchmark-gocache/gcstats"
+	"benchmark-gocache/histogram"
+	"benchmark-gocache/workload"
+)
+
+const (
+	// streamLen is the number of pre-generated operations shared by the
+	// goroutines of a run. It must be a power of two.
+	streamLen = 1 << 20
+
+	// goroutineStride spreads the starting operation of each goroutine so
+	// they do not walk the stream in lockstep.
+	goroutineStride = 7919
+)
+
+// Config describes one run.
+type Config struct {
+	Cache      string        // registered cache name
+	Mix        workload.Mix  // read/write/delete ratio
+	Keys       workload.Spec // key distribution and keyspace
+	Duration   time.Duration // how long to run
+	Goroutines int           // concurrent workers
+	KeySize    int           // keys shorter than this are padded to it
+	ValueSize  int           // bytes per stored value
+	TTL        time.Duration // ttl for every Set; 0 stores without expiration
+}
+
+// Workload returns the name of the mix and key distribution of c, such as
+// "r90-w10/zipf-0.99".
+func (c Config) Workload() string { return c.Mix.String() + "/" + c.Keys.String() }
+
+// Result is the outcome of one run. Durations are in nanoseconds.
+type Result struct {
+	Cache      string `json:"cache"`
+	Workload   string `json:"workload"`
+	Goroutines int    `json:"goroutines"`
+	KeySize    int    `json:"key_size"`
+	ValueSize  int    `json:"value_size"`
+
+	Ops         uint64        `json:"ops"`
+	Elapsed     time.Duration `json:"elapsed_ns"`
+	OpsPerSec   float64       `json:"ops_per_sec"`
+	NsPerOp     float64       `json:"ns_per_op"`
+	HitRatio    float64       `json:"hit_ratio"`
+	P50         time.Duration `json:"p50_ns"`
+	P90         time.Duration `json:"p90_ns"`
+	P99         time.Duration `json:"p99_ns"`
+	P999        time.Duration `json:"p999_ns"`
+	Max         time.Duration `json:"max_ns"`
+	AllocsPerOp float64       `json:"allocs_per_op"`
+	BytesPerOp  float64       `json:"bytes_per_op"`
+	GCCycles    uint64        `json:"gc_cycles"`
+	GCPause     time.Duration `json:"gc_pause_ns"`
+}
+
+// Name identifies the benchmark r measures, independently of its numbers,
+// such as "v9/r90-w10/zipf-0.99/g8/k16-v64".
+func (r Result) Name() string {
+	return fmt.Sprintf("%s/%s/g%d/k%d-v%d", r.Cache, r.Workload, r.Goroutines, r.KeySize, r.ValueSize)
+}
+
+// Run builds cfg.Cache, fills it with every key of the keyspace and then
+// runs cfg.Mix from cfg.Goroutines goroutines for cfg.Duration. Every
+// operation is timed individually for the latency percentiles, which adds
+// the cost of two clock reads to NsPerOp.
+func Run(cfg Config) (Result, error) {
+	if cfg.Goroutines < 1 {
+		return Result{}, fmt.Errorf("bench: goroutines must be at least 1, got %d", cfg.Goroutines)
+	}
+	if cfg.Duration <= 0 {
+		return Result{}, fmt.Errorf("bench: duration must be positive, got %v", cfg.Duration)
+	}
+	g, err := workload.New(cfg.Keys)
+	if err != nil {
+		return Result{}, err
+	}
+	ops := workload.Ops(cfg.Mix, g, streamLen, cfg.Keys.Seed)
+	for i := range ops {
+		ops[i].Key = padKey(ops[i].Key, cfg.KeySize)
+	}
+	val := make([]byte, cfg.ValueSize)
+
+	c, err := adapter.New(cfg.Cache, adapter.Config{TTL: cfg.TTL})
+	if err != nil {
+		return Result{}, err
+	}
+	defer c.Close()
+	for i := 0; i < cfg.Keys.Keys; i++ {
+		c.Set(padKey(workload.KeyName(uint64(i)), cfg.KeySize), val, cfg.TTL)
+	}
+	adapter.Wait(c)
+
+	var (
+		stop       atomic.Bool
+		wg         sync.WaitGroup
+		mu         sync.Mutex
+		latency    histogram.Histogram
+		gets, hits uint64
+	)
+	before := gcstats.Read()
+	start := time.Now()
+	timer := time.AfterFunc(cfg.Duration, func() { stop.Store(true) })
+	defer timer.Stop()
+	for w := 0; w < cfg.Goroutines; w++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var (
+				h    histogram.Histogram
+				g, n uint64
+			)
+			for ; !stop.Load(); i++ {
+				op := ops[i&(streamLen-1)]
+				opStart := time.Now()
+				switch op.Kind {
+				case workload.OpGet:
+					g++
+					if _, ok := c.Get(op.Key); ok {
+						n++
+					}
+				case workload.OpSet:
+					c.Set(op.Key, val, cfg.TTL)
+				case workload.OpDelete:
+					c.Delete(op.Key)
+				}
+				h.Record(time.Since(opStart))
+			}
+			mu.Lock()
+			latency.Merge(&h)
+			gets += g
+			hits += n
+			mu.Unlock()
+		}(w * goroutineStride)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	gc := gcstats.Read().Sub(before)
+
+	r := Result{
+		Cache:      cfg.Cache,
+		Workload:   cfg.Workload(),
+		Goroutines: cfg.Goroutines,
+		KeySize:    cfg.KeySize,
+		ValueSize:  cfg.ValueSize,
+		Ops:        latency.Count(),
+		Elapsed:    elapsed,
+		P50:        latency.Quantile(0.50),
+		P90:        latency.Quantile(0.90),
+		P99:        latency.Quantile(0.99),
+		P999:       latency.Quantile(0.999),
+		Max:        latency.Max(),
+		GCCycles:   gc.Cycles,
+		GCPause:    gc.Pause,
+	}
+	if r.Ops > 0 {
+		n := float64(r.Ops)
+		r.OpsPerSec = n / elapsed.Seconds()
+		r.NsPerOp = float64(elapsed) / n
+		r.AllocsPerOp = float64(gc.Allocs) / n
+		r.BytesPerOp = float64(gc.AllocBytes) / n
+	}
+	if gets > 0 {
+		r.HitRatio = float64(hits) / float64(gets)
+	}
+	return r, nil
+}
+
+// padKey left-pads key with 'x' to size bytes. Keys are never truncated,
+// so they stay unique.
+func padKey(key string, size int) string {
+	if len(key) >= size {
+		return key
+	}
+	return strings.Repeat("x", size-len(key)) + key
+}
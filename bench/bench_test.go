@@ -0,0 +1,125 @@
+package bench
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"benchmark-gocache/workload"
+)
+
+func testConfig() Config {
+	return Config{
+		Cache:      "v9",
+		Mix:        workload.Mix{Read: 90, Write: 10},
+		Keys:       workload.Spec{Distribution: workload.Uniform, Keys: 1000, Seed: 1},
+		Duration:   50 * time.Millisecond,
+		Goroutines: 2,
+		KeySize:    16,
+		ValueSize:  32,
+		TTL:        time.Minute,
+	}
+}
+
+func TestRun(t *testing.T) {
+	r, err := Run(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Ops == 0 || r.OpsPerSec <= 0 || r.NsPerOp <= 0 {
+		t.Errorf("Ops = %d, OpsPerSec = %v, NsPerOp = %v, want positive", r.Ops, r.OpsPerSec, r.NsPerOp)
+	}
+	// Every key is pre-populated and nothing is deleted, so every Get hits.
+	if r.HitRatio != 1 {
+		t.Errorf("HitRatio = %v, want 1", r.HitRatio)
+	}
+	if r.P50 <= 0 || r.P50 > r.P99 || r.P99 > r.Max {
+		t.Errorf("percentiles out of order: p50=%v p99=%v max=%v", r.P50, r.P99, r.Max)
+	}
+	if want := "v9/r90-w10/uniform/g2/k16-v32"; r.Name() != want {
+		t.Errorf("Name() = %q, want %q", r.Name(), want)
+	}
+}
+
+func TestRun_Invalid(t *testing.T) {
+	tests := []func(*Config){
+		func(c *Config) { c.Cache = "nope" },
+		func(c *Config) { c.Goroutines = 0 },
+		func(c *Config) { c.Duration = 0 },
+		func(c *Config) { c.Keys.Keys = 0 },
+	}
+	for i, modify := range tests {
+		cfg := testConfig()
+		modify(&cfg)
+		if _, err := Run(cfg); err == nil {
+			t.Errorf("case %d: Run(%+v) expected an error", i, cfg)
+		}
+	}
+}
+
+func TestPadKey(t *testing.T) {
+	if got := padKey("key-1", 8); got != "xxxkey-1" {
+		t.Errorf("padKey(key-1, 8) = %q", got)
+	}
+	if got := padKey("key-12345", 4); got != "key-12345" {
+		t.Errorf("padKey must not truncate, got %q", got)
+	}
+}
+
+func testReport() Report {
+	return Report{
+		Env: Env{CPU: "Test CPU", NumCPU: 4, GOMAXPROCS: 4, GOOS: "linux", GOARCH: "amd64", GoVersion: "go1.22"},
+		Results: []Result{
+			{Cache: "v9", Workload: "r90-w10/zipf-0.99", Goroutines: 4, KeySize: 16, ValueSize: 64, Ops: 1000, OpsPerSec: 1e6, P50: 120},
+			{Cache: "v11", Workload: "r90-w10/zipf-0.99", Goroutines: 4, KeySize: 16, ValueSize: 64, Ops: 2000, OpsPerSec: 2e6, P50: 80},
+		},
+	}
+}
+
+func TestWriteJSON_RoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, testReport()); err != nil {
+		t.Fatal(err)
+	}
+	var got Report
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := testReport()
+	if got.Env.CPU != want.Env.CPU || len(got.Results) != 2 || got.Results[1] != want.Results[1] {
+		t.Errorf("round trip = %+v, want %+v", got, want)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, testReport()); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("got %d rows, want header and 2 results", len(rows))
+	}
+	if rows[1][0] != "v9" || rows[2][7] != "2000000" {
+		t.Errorf("unexpected rows: %v", rows[1:])
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, testReport()); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{"Test CPU", "linux/amd64", "GOMAXPROCS=4", "| v11 | r90-w10/zipf-0.99 | 4 | 16/64 | 2000000 |"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Markdown output is missing %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"text/tabwriter"
+)
+
+// ReportVersion is the version of the JSON report format written by
+// WriteJSON. ReadReport rejects reports of any other version.
+const ReportVersion = 1
+
+// ReadReport reads a JSON report written by WriteJSON.
+func ReadReport(r io.Reader) (Report, error) {
+	var rep Report
+	if err := json.NewDecoder(r).Decode(&rep); err != nil {
+		return Report{}, fmt.Errorf("bench: reading report: %w", err)
+	}
+	if rep.Version != ReportVersion {
+		return Report{}, fmt.Errorf("bench: unsupported report version %d, want %d", rep.Version, ReportVersion)
+	}
+	return rep, nil
+}
+
+// Metric is a result value compared between runs. Lower is better for
+// every metric.
+type Metric struct {
+	Name  string
+	Value func(Result) float64
+}
+
+// Metrics are the values Compare looks at.
+var Metrics = []Metric{
+	{Name: "ns/op", Value: func(r Result) float64 { return r.NsPerOp }},
+	{Name: "p99-ns", Value: func(r Result) float64 { return float64(r.P99) }},
+	{Name: "allocs/op", Value: func(r Result) float64 { return r.AllocsPerOp }},
+}
+
+// CompareOptions control when a change counts as a regression.
+type CompareOptions struct {
+	// Threshold is the relative slowdown of the median, such as 0.05 for
+	// 5%, beyond which a significant change is a regression.
+	Threshold float64
+
+	// Alpha is the significance level: a change counts only when the
+	// Mann-Whitney U test gives a p-value below it. Zero means 0.05.
+	Alpha float64
+}
+
+// Delta compares one metric of one benchmark between a baseline and a new run.
+type Delta struct {
+	Name        string    // benchmark name, see Result.Name
+	Metric      string    // metric name, see Metrics
+	Base, Head  []float64 // samples
+	Change      float64   // relative change of the median; positive is slower
+	P           float64   // two-sided Mann-Whitney U p-value
+	Significant bool      // P is below the significance level
+	Regression  bool      // significant and Change is above the threshold
+}
+
+// Compare matches the results of head to those of base by name, treating
+// results that share a name as samples of the same benchmark, and compares
+// every Metric. Benchmarks missing from either report are left out. At
+// least four samples on each side are needed for a change to be significant
+// at the default level.
+func Compare(base, head Report, opts CompareOptions) []Delta {
+	alpha := opts.Alpha
+	if alpha == 0 {
+		alpha = 0.05
+	}
+	baseByName, _ := group(base.Results)
+	headByName, names := group(head.Results)
+
+	var deltas []Delta
+	for _, name := range names {
+		b, ok := baseByName[name]
+		if !ok {
+			continue
+		}
+		h := headByName[name]
+		for _, m := range Metrics {
+			d := Delta{Name: name, Metric: m.Name, Base: values(b, m), Head: values(h, m)}
+			if bm := median(d.Base); bm != 0 {
+				d.Change = (median(d.Head) - bm) / bm
+			}
+			d.P = mannWhitneyU(d.Base, d.Head)
+			d.Significant = d.P < alpha
+			d.Regression = d.Significant && d.Change > opts.Threshold
+			deltas = append(deltas, d)
+		}
+	}
+	return deltas
+}
+
+// Regressions returns the deltas that are regressions.
+func Regressions(deltas []Delta) []Delta {
+	var regs []Delta
+	for _, d := range deltas {
+		if d.Regression {
+			regs = append(regs, d)
+		}
+	}
+	return regs
+}
+
+// WriteComparison writes deltas as a table in the style of benchstat:
+// medians with their spread, the change, and "~" when the change is not
+// significant.
+func WriteComparison(w io.Writer, deltas []Delta) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "name\tmetric\told\tnew\tdelta\t")
+	for _, d := range deltas {
+		change := "~"
+		if d.Significant {
+			change = fmt.Sprintf("%+.2f%%", d.Change*100)
+		}
+		note := fmt.Sprintf("(p=%.3f n=%d+%d)", d.P, len(d.Base), len(d.Head))
+		if d.Regression {
+			note += " REGRESSION"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s %s\t\n",
+			d.Name, d.Metric, summary(d.Base), summary(d.Head), change, note)
+	}
+	return tw.Flush()
+}
+
+// group collects results by name, returning the names in first-seen order.
+func group(results []Result) (map[string][]Result, []string) {
+	byName := make(map[string][]Result)
+	var names []string
+	for _, r := range results {
+		name := r.Name()
+		if _, ok := byName[name]; !ok {
+			names = append(names, name)
+		}
+		byName[name] = append(byName[name], r)
+	}
+	return byName, names
+}
+
+func values(rs []Result, m Metric) []float64 {
+	vs := make([]float64, len(rs))
+	for i, r := range rs {
+		vs[i] = m.Value(r)
+	}
+	return vs
+}
+
+func median(xs []float64) float64 {
+	if len(xs) == 0 {
+		return 0
+	}
+	s := append([]float64(nil), xs...)
+	sort.Float64s(s)
+	n := len(s)
+	if n%2 == 1 {
+		return s[n/2]
+	}
+	return (s[n/2-1] + s[n/2]) / 2
+}
+
+// summary formats the median of xs and the largest deviation from it.
+func summary(xs []float64) string {
+	m := median(xs)
+	var dev float64
+	for _, x := range xs {
+		dev = math.Max(dev, math.Abs(x-m))
+	}
+	if m == 0 {
+		return fmt.Sprintf("%.4g", m)
+	}
+	return fmt.Sprintf("%.4g ±%.0f%%", m, dev/m*100)
+}
+
+// mannWhitneyU returns the two-sided p-value of the Mann-Whitney U test
+// that x and y come from the same distribution. It uses the exact
+// distribution of U for small samples without ties, and the normal
+// approximation with tie and continuity corrections otherwise.
+func mannWhitneyU(x, y []float64) float64 {
+	n1, n2 := len(x), len(y)
+	if n1 == 0 || n2 == 0 {
+		return 1
+	}
+
+	type obs struct {
+		v     float64
+		fromX bool
+	}
+	all := make([]obs, 0, n1+n2)
+	for _, v := range x {
+		all = append(all, obs{v, true})
+	}
+	for _, v := range y {
+		all = append(all, obs{v, false})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].v < all[j].v })
+
+	// Rank with ties sharing their average rank.
+	var rankX, tieSum float64
+	ties := false
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].v == all[i].v {
+			j++
+		}
+		rank := float64(i+j+1) / 2 // average of ranks i+1..j
+		for k := i; k < j; k++ {
+			if all[k].fromX {
+				rankX += rank
+			}
+		}
+		if t := float64(j - i); t > 1 {
+			ties = true
+			tieSum += t*t*t - t
+		}
+		i = j
+	}
+
+	u1 := rankX - float64(n1*(n1+1))/2
+	u := math.Min(u1, float64(n1*n2)-u1)
+
+	if !ties && n1+n2 <= exactLimit {
+		return math.Min(1, 2*uCDF(n1, n2, int(u)))
+	}
+
+	n := float64(n1 + n2)
+	sigma := math.Sqrt(float64(n1*n2) / 12 * ((n + 1) - tieSum/(n*(n-1))))
+	if sigma == 0 {
+		return 1
+	}
+	z := (u - float64(n1*n2)/2 + 0.5) / sigma
+	return math.Min(1, math.Erfc(-z/math.Sqrt2))
+}
+
+// exactLimit is the largest combined sample size for which mannWhitneyU
+// computes the exact distribution of U.
+const exactLimit = 50
+
+// uCDF returns P(U <= u) for samples of sizes n1 and n2 under the null
+// hypothesis, counting the orderings of the samples that give each U.
+func uCDF(n1, n2, u int) float64 {
+	// counts[i][j][k] is the number of orderings of i x's and j y's with U = k.
+	counts := make([][][]float64, n1+1)
+	for i := range counts {
+		counts[i] = make([][]float64, n2+1)
+		for j := range counts[i] {
+			c := make([]float64, i*j+1)
+			switch {
+			case i == 0 || j == 0:
+				c[0] = 1
+			default:
+				// The largest value is either an x, beating all j y's,
+				// or a y, beating nothing.
+				for k, n := range counts[i-1][j] {
+					c[k+j] += n
+				}
+				for k, n := range counts[i][j-1] {
+					c[k] += n
+				}
+			}
+			counts[i][j] = c
+		}
+	}
+
+	var below, total float64
+	for k, n := range counts[n1][n2] {
+		if k <= u {
+			below += n
+		}
+		total += n
+	}
+	return below / total
+}
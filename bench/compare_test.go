@@ -0,0 +1,116 @@
+package bench
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestMannWhitneyU(t *testing.T) {
+	tests := []struct {
+		name string
+		x, y []float64
+		want float64
+	}{
+		// Exact: 2 of the C(10,5) = 252 orderings are at least this extreme.
+		{"separated", []float64{1, 2, 3, 4, 5}, []float64{6, 7, 8, 9, 10}, 2.0 / 252},
+		{"interleaved", []float64{1, 3, 5, 7}, []float64{2, 4, 6, 8}, 0.6857},
+		{"identical", []float64{5, 5, 5}, []float64{5, 5, 5}, 1},
+		{"single", []float64{1}, []float64{2}, 1},
+		{"empty", nil, []float64{1, 2}, 1},
+	}
+	for _, tt := range tests {
+		if got := mannWhitneyU(tt.x, tt.y); math.Abs(got-tt.want) > 1e-3 {
+			t.Errorf("%s: mannWhitneyU = %.4f, want %.4f", tt.name, got, tt.want)
+		}
+	}
+
+	// With ties the normal approximation is used; clearly separated
+	// samples must still be significant.
+	x := []float64{10, 10, 11, 11, 12, 12}
+	y := []float64{20, 20, 21, 21, 22, 22}
+	if p := mannWhitneyU(x, y); p >= 0.01 {
+		t.Errorf("tied separated samples: p = %.4f, want < 0.01", p)
+	}
+}
+
+// samples returns a report with one result per ns/op value.
+func samples(cache string, nsPerOp ...float64) Report {
+	var r Report
+	for _, ns := range nsPerOp {
+		r.Results = append(r.Results, Result{Cache: cache, Workload: "r90-w10/zipf-0.99", Goroutines: 1, NsPerOp: ns})
+	}
+	return r
+}
+
+func TestCompare(t *testing.T) {
+	base := samples("v9", 100, 101, 99, 100, 102)
+	opts := CompareOptions{Threshold: 0.05}
+
+	tests := []struct {
+		name       string
+		head       Report
+		regression bool
+	}{
+		{"slower", samples("v9", 120, 121, 119, 122, 120), true},
+		{"noise", samples("v9", 101, 99, 100, 102, 100), false},
+		{"faster", samples("v9", 80, 81, 79, 80, 82), false},
+		{"slower below threshold", samples("v9", 103, 103, 104, 103, 104), false},
+		{"too few samples", samples("v9", 150), false},
+	}
+	for _, tt := range tests {
+		head := tt.head
+		deltas := Compare(base, head, opts)
+		if len(deltas) != len(Metrics) {
+			t.Fatalf("%s: got %d deltas, want one per metric", tt.name, len(deltas))
+		}
+		d := deltas[0] // ns/op
+		if d.Regression != tt.regression {
+			t.Errorf("%s: Regression = %v (change %+.2f%%, p=%.3f), want %v",
+				tt.name, d.Regression, d.Change*100, d.P, tt.regression)
+		}
+		if got := len(Regressions(deltas)) > 0; got != tt.regression {
+			t.Errorf("%s: Regressions() non-empty = %v, want %v", tt.name, got, tt.regression)
+		}
+	}
+
+	if deltas := Compare(base, samples("v11", 100), opts); len(deltas) != 0 {
+		t.Errorf("benchmarks missing from the baseline should be skipped, got %v", deltas)
+	}
+}
+
+func TestReadReport(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, samples("v9", 100, 110)); err != nil {
+		t.Fatal(err)
+	}
+	r, err := ReadReport(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Version != ReportVersion || len(r.Results) != 2 {
+		t.Errorf("ReadReport = version %d with %d results", r.Version, len(r.Results))
+	}
+
+	if _, err := ReadReport(strings.NewReader(`{"version": 99, "results": []}`)); err == nil {
+		t.Error("ReadReport accepted an unknown version")
+	}
+	if _, err := ReadReport(strings.NewReader(`{"results": []}`)); err == nil {
+		t.Error("ReadReport accepted a report without a version")
+	}
+}
+
+func TestWriteComparison(t *testing.T) {
+	deltas := Compare(samples("v9", 100, 101, 99, 100, 102), samples("v9", 120, 121, 119, 122, 120), CompareOptions{Threshold: 0.05})
+	var buf bytes.Buffer
+	if err := WriteComparison(&buf, deltas); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{"v9/r90-w10/zipf-0.99/g1/k0-v0", "+20.00%", "REGRESSION", "n=5+5", "~"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("comparison is missing %q:\n%s", want, out)
+		}
+	}
+}
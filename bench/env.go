@@ -0,0 +1,59 @@
+package bench
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Env describes the machine and runtime a report was produced on.
+type Env struct {
+	CPU        string    `json:"cpu"`
+	NumCPU     int       `json:"num_cpu"`
+	GOMAXPROCS int       `json:"gomaxprocs"`
+	GOOS       string    `json:"goos"`
+	GOARCH     string    `json:"goarch"`
+	GoVersion  string    `json:"go_version"`
+	Time       time.Time `json:"time"`
+}
+
+// CurrentEnv returns the environment of the running process.
+func CurrentEnv() Env {
+	return Env{
+		CPU:        cpuModel(),
+		NumCPU:     runtime.NumCPU(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		GOOS:       runtime.GOOS,
+		GOARCH:     runtime.GOARCH,
+		GoVersion:  runtime.Version(),
+		Time:       time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+// cpuModel returns the CPU model name, or "unknown" when it cannot be found.
+func cpuModel() string {
+	switch runtime.GOOS {
+	case "linux":
+		f, err := os.Open("/proc/cpuinfo")
+		if err != nil {
+			break
+		}
+		defer f.Close()
+		s := bufio.NewScanner(f)
+		for s.Scan() {
+			name, value, ok := strings.Cut(s.Text(), ":")
+			if ok && strings.TrimSpace(name) == "model name" {
+				return strings.TrimSpace(value)
+			}
+		}
+	case "darwin":
+		out, err := exec.Command("sysctl", "-n", "machdep.cpu.brand_string").Output()
+		if err == nil {
+			return strings.TrimSpace(string(out))
+		}
+	}
+	return "unknown"
+}
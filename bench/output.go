@@ -0,0 +1,79 @@
+package bench
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Report is a set of results together with the environment they were
+// measured in.
+type Report struct {
+	Version int      `json:"version"` // see ReportVersion
+	Env     Env      `json:"env"`
+	Results []Result `json:"results"`
+}
+
+// WriteJSON writes r as indented JSON, stamped with ReportVersion.
+func WriteJSON(w io.Writer, r Report) error {
+	r.Version = ReportVersion
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// csvHeader names the columns written by WriteCSV.
+var csvHeader = []string{
+	"cache", "workload", "goroutines", "key_size", "value_size",
+	"ops", "elapsed_ns", "ops_per_sec", "ns_per_op", "hit_ratio",
+	"p50_ns", "p90_ns", "p99_ns", "p999_ns", "max_ns",
+	"allocs_per_op", "bytes_per_op", "gc_cycles", "gc_pause_ns",
+}
+
+// WriteCSV writes one row per result, after a header row. The environment
+// is not part of the CSV; keep the JSON report for it.
+func WriteCSV(w io.Writer, r Report) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	ns := func(d time.Duration) string { return strconv.FormatInt(int64(d), 10) }
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	for _, res := range r.Results {
+		row := []string{
+			res.Cache, res.Workload, strconv.Itoa(res.Goroutines), strconv.Itoa(res.KeySize), strconv.Itoa(res.ValueSize),
+			strconv.FormatUint(res.Ops, 10), ns(res.Elapsed), f(res.OpsPerSec), f(res.NsPerOp), f(res.HitRatio),
+			ns(res.P50), ns(res.P90), ns(res.P99), ns(res.P999), ns(res.Max),
+			f(res.AllocsPerOp), f(res.BytesPerOp), strconv.FormatUint(res.GCCycles, 10), ns(res.GCPause),
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteMarkdown writes the environment as a list followed by a results
+// table, ready to paste into the README.
+func WriteMarkdown(w io.Writer, r Report) error {
+	e := r.Env
+	fmt.Fprintf(w, "- CPU: %s (%d cores)\n", e.CPU, e.NumCPU)
+	fmt.Fprintf(w, "- OS/Arch: %s/%s\n", e.GOOS, e.GOARCH)
+	fmt.Fprintf(w, "- Go: %s, GOMAXPROCS=%d\n", e.GoVersion, e.GOMAXPROCS)
+	fmt.Fprintf(w, "- Date: %s\n\n", e.Time.Format(time.RFC3339))
+
+	fmt.Fprintln(w, "| Cache | Workload | Goroutines | Key/Value (B) | Ops/s | ns/op | Hit ratio | p50 | p99 | p99.9 | allocs/op | B/op |")
+	fmt.Fprintln(w, "|-------|----------|-----------:|--------------:|------:|------:|----------:|----:|----:|------:|----------:|-----:|")
+	for _, res := range r.Results {
+		fmt.Fprintf(w, "| %s | %s | %d | %d/%d | %.0f | %.1f | %.4f | %v | %v | %v | %.2f | %.1f |\n",
+			res.Cache, res.Workload, res.Goroutines, res.KeySize, res.ValueSize,
+			res.OpsPerSec, res.NsPerOp, res.HitRatio, res.P50, res.P99, res.P999,
+			res.AllocsPerOp, res.BytesPerOp)
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
@@ -0,0 +1,69 @@
+// Command benchcmp compares two JSON reports written by cachebench -json,
+// in the style of benchstat, and exits with status 1 when a benchmark in
+// the new report is significantly slower than in the old one by more than
+// -threshold.
+//
+// Usage:
+//
+//	benchcmp [-threshold 0.05] [-alpha 0.05] old.json new.json
+//
+// Run cachebench with -count 5 or more so the comparison has enough samples
+// to tell a change from noise.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"benchmark-gocache/bench"
+)
+
+func main() {
+	var (
+		threshold = flag.Float64("threshold", 0.05, "relative slowdown beyond which a significant change is a regression")
+		alpha     = flag.Float64("alpha", 0.05, "significance level of the Mann-Whitney U test")
+	)
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: benchcmp [flags] old.json new.json")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	log.SetFlags(0)
+	log.SetPrefix("benchcmp: ")
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	base, err := readReport(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	head, err := readReport(flag.Arg(1))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	deltas := bench.Compare(base, head, bench.CompareOptions{Threshold: *threshold, Alpha: *alpha})
+	if len(deltas) == 0 {
+		log.Fatal("the reports have no benchmarks in common")
+	}
+	if err := bench.WriteComparison(os.Stdout, deltas); err != nil {
+		log.Fatal(err)
+	}
+	if regs := bench.Regressions(deltas); len(regs) > 0 {
+		log.Fatalf("%d regressions beyond %.1f%%", len(regs), *threshold*100)
+	}
+}
+
+// readReport reads the JSON report at path.
+func readReport(path string) (bench.Report, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return bench.Report{}, err
+	}
+	defer f.Close()
+	return bench.ReadReport(f)
+}
@@ -0,0 +1,196 @@
+// Command cachebench runs configurable workloads against the caches of this
+// repository and writes the results as JSON, CSV and a Markdown table that
+// carries the environment (CPU, GOOS/GOARCH, Go version, GOMAXPROCS).
+//
+// Every combination of -caches, -mixes, -dist, -goroutines, -key-sizes and
+// -value-sizes is run -count times for -duration.
+//
+// With -baseline the run is compared against a JSON report saved earlier
+// with -json, and cachebench exits with status 1 when a benchmark became
+// significantly slower than -threshold. See bench.Compare.
+//
+// Usage:
+//
+//	cachebench -caches v9,v11,ristretto -mixes 90/10,50/50 -goroutines 1,8
+//	cachebench -json results.json -csv results.csv -markdown results.md
+//	cachebench -caches v9,v11 -count 10 -json base.json
+//	cachebench -caches v9,v11 -count 10 -baseline base.json -threshold 0.05
+//
+// Without -json, -csv, -markdown or -baseline the Markdown table is written
+// to stdout.
+package main
+
+import (
+	"flag"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"benchmark-gocache/adapter"
+	"benchmark-gocache/bench"
+	"benchmark-gocache/workload"
+)
+
+func main() {
+	var (
+		caches     = flag.String("caches", "", "comma-separated caches to run (default: all registered)")
+		mixes      = flag.String("mixes", "90/10", "comma-separated read/write[/delete] ratios")
+		dists      = flag.String("dist", "zipf", "comma-separated key distributions: uniform, zipf, hotspot, latest or scan")
+		keys       = flag.Int("keys", 100000, "keyspace size; every key is stored before a run starts")
+		skew       = flag.Float64("skew", workload.DefaultSkew, "Zipf and latest skew")
+		duration   = flag.Duration("duration", time.Second, "duration of each run")
+		count      = flag.Int("count", 1, "samples per benchmark; comparisons need at least 4")
+		goroutines = flag.String("goroutines", "1", "comma-separated goroutine counts")
+		keySizes   = flag.String("key-sizes", "16", "comma-separated key sizes in bytes")
+		valueSizes = flag.String("value-sizes", "64", "comma-separated value sizes in bytes")
+		ttl        = flag.Duration("ttl", 10*time.Minute, "ttl for every Set; 0 disables expiration")
+		jsonOut    = flag.String("json", "", "write the JSON report to this file, or - for stdout")
+		csvOut     = flag.String("csv", "", "write the CSV results to this file, or - for stdout")
+		mdOut      = flag.String("markdown", "", "write the Markdown table to this file, or - for stdout")
+		baseline   = flag.String("baseline", "", "compare against this JSON report and exit 1 on regressions")
+		threshold  = flag.Float64("threshold", 0.05, "relative slowdown beyond which a significant change is a regression")
+	)
+	flag.Parse()
+	log.SetFlags(0)
+	log.SetPrefix("cachebench: ")
+
+	names, err := adapter.Select(*caches)
+	if err != nil {
+		log.Fatal(err)
+	}
+	ms, err := workload.ParseMixes(*mixes)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var specs []workload.Spec
+	for _, f := range splitList(*dists) {
+		d, err := workload.ParseDistribution(f)
+		if err != nil {
+			log.Fatal(err)
+		}
+		specs = append(specs, workload.Spec{Distribution: d, Keys: *keys, Skew: *skew, Seed: 1})
+	}
+	if *count < 1 {
+		log.Fatalf("invalid -count %d", *count)
+	}
+	var base bench.Report
+	if *baseline != "" {
+		if base, err = readReport(*baseline); err != nil {
+			log.Fatal(err)
+		}
+	}
+	gs := parseInts("goroutines", *goroutines)
+	ks := parseInts("key-sizes", *keySizes)
+	vs := parseInts("value-sizes", *valueSizes)
+
+	report := bench.Report{Env: bench.CurrentEnv()}
+	for _, m := range ms {
+		for _, s := range specs {
+			for _, g := range gs {
+				for _, k := range ks {
+					for _, v := range vs {
+						for _, name := range names {
+							cfg := bench.Config{
+								Cache: name, Mix: m, Keys: s, Duration: *duration,
+								Goroutines: g, KeySize: k, ValueSize: v, TTL: *ttl,
+							}
+							for i := 0; i < *count; i++ {
+								r, err := bench.Run(cfg)
+								if err != nil {
+									log.Fatalf("%s: %v", name, err)
+								}
+								log.Printf("%s\t%.0f ops/s\t%.1f ns/op", r.Name(), r.OpsPerSec, r.NsPerOp)
+								report.Results = append(report.Results, r)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+
+	if *jsonOut == "" && *csvOut == "" && *mdOut == "" && *baseline == "" {
+		*mdOut = "-"
+	}
+	for _, out := range []struct {
+		path  string
+		write func(io.Writer, bench.Report) error
+	}{
+		{*jsonOut, bench.WriteJSON},
+		{*csvOut, bench.WriteCSV},
+		{*mdOut, bench.WriteMarkdown},
+	} {
+		if out.path == "" {
+			continue
+		}
+		if err := writeFile(out.path, report, out.write); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *baseline != "" {
+		deltas := bench.Compare(base, report, bench.CompareOptions{Threshold: *threshold})
+		if err := bench.WriteComparison(os.Stdout, deltas); err != nil {
+			log.Fatal(err)
+		}
+		if regs := bench.Regressions(deltas); len(regs) > 0 {
+			log.Fatalf("%d regressions beyond %.1f%% against %s", len(regs), *threshold*100, *baseline)
+		}
+	}
+}
+
+// readReport reads the JSON report at path.
+func readReport(path string) (bench.Report, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return bench.Report{}, err
+	}
+	defer f.Close()
+	return bench.ReadReport(f)
+}
+
+// writeFile writes r to path with write, or to stdout when path is "-".
+func writeFile(path string, r bench.Report, write func(io.Writer, bench.Report) error) error {
+	if path == "-" {
+		return write(os.Stdout, r)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// splitList splits a comma-separated flag value, dropping empty fields.
+func splitList(s string) []string {
+	var fields []string
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// parseInts parses the comma-separated positive integers of flag name.
+func parseInts(name, s string) []int {
+	var ns []int
+	for _, f := range splitList(s) {
+		n, err := strconv.Atoi(f)
+		if err != nil || n < 1 {
+			log.Fatalf("invalid -%s value %q", name, f)
+		}
+		ns = append(ns, n)
+	}
+	if len(ns) == 0 {
+		log.Fatalf("-%s is empty", name)
+	}
+	return ns
+}
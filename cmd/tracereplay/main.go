@@ -0,0 +1,121 @@
+// Command tracereplay replays a recorded cache access trace against the
+// caches of this repository and reports hit ratio, throughput and latency.
+//
+// Usage:
+//
+//	tracereplay -trace access.csv -caches v1,v9,ristretto
+//	tracereplay -trace OLTP.lis -format arc -ttl 0
+//
+// See package trace for the supported formats.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"benchmark-gocache/adapter"
+	"benchmark-gocache/trace"
+	"benchmark-gocache/workload"
+)
+
+func main() {
+	var (
+		path      = flag.String("trace", "-", "trace file to replay, or - for stdin")
+		format    = flag.String("format", "csv", "trace format: csv, lirs or arc")
+		caches    = flag.String("caches", "", "comma-separated caches to replay against (default: all registered)")
+		ttl       = flag.Duration("ttl", 10*time.Minute, "TTL for fills and for records without one; 0 disables expiration")
+		valueSize = flag.Int("value-size", trace.DefaultValueSize, "value size in bytes for records without one")
+		fill      = flag.String("fill", "auto", "set a key after a Get miss: true, false or auto (true for key-only formats)")
+		realtime  = flag.Bool("realtime", false, "honour record timestamps instead of replaying as fast as possible")
+	)
+	flag.Parse()
+	log.SetFlags(0)
+	log.SetPrefix("tracereplay: ")
+
+	f, err := trace.ParseFormat(*format)
+	if err != nil {
+		log.Fatal(err)
+	}
+	names, err := adapter.Select(*caches)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	opts := trace.Options{ValueSize: *valueSize, TTL: *ttl, Realtime: *realtime}
+	switch *fill {
+	case "auto":
+		opts.FillOnMiss = f.KeyOnly()
+	case "true":
+		opts.FillOnMiss = true
+	case "false":
+	default:
+		log.Fatalf("invalid -fill %q", *fill)
+	}
+
+	recs, err := load(*path, f)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(recs) == 0 {
+		log.Fatal("trace is empty")
+	}
+
+	results := make([]trace.Result, len(names))
+	for i, name := range names {
+		c, err := adapter.New(name, adapter.Config{TTL: *ttl})
+		if err != nil {
+			log.Fatal(err)
+		}
+		results[i] = trace.Replay(c, recs, opts)
+		c.Close()
+	}
+	report(os.Stdout, names, results)
+}
+
+// load reads the whole trace before replaying so parsing is never timed.
+func load(path string, f trace.Format) ([]trace.Record, error) {
+	var in io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		in = file
+	}
+	r, err := trace.NewReader(f, in)
+	if err != nil {
+		return nil, err
+	}
+	return trace.ReadAll(r)
+}
+
+func report(out io.Writer, names []string, results []trace.Result) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "cache\tops\tgets\thit ratio\tfills\telapsed\tops/s\t")
+	for i, r := range results {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%.4f\t%d\t%v\t%.0f\t\n",
+			names[i], r.Total(), r.Ops[workload.OpGet], r.HitRatio(), r.Fills,
+			r.Elapsed.Round(time.Microsecond), r.Throughput())
+	}
+	w.Flush()
+	fmt.Fprintln(out)
+
+	fmt.Fprintln(w, "cache\top\tcount\tp50\tp90\tp99\tp99.9\tmax\t")
+	for i, r := range results {
+		for _, k := range workload.OpKinds {
+			if r.Ops[k] == 0 {
+				continue
+			}
+			l := r.Latency[k]
+			fmt.Fprintf(w, "%s\t%s\t%d\t%v\t%v\t%v\t%v\t%v\t\n",
+				names[i], k, r.Ops[k], l.P50, l.P90, l.P99, l.P999, l.Max)
+		}
+	}
+	w.Flush()
+}
@@ -0,0 +1,215 @@
+// Package conformance is a test suite that every gocache version must pass,
+// so that they can be compared as implementations of the same cache.
+//
+// The expected semantics are:
+//
+//   - Get of a key that was never set, was deleted or has expired misses.
+//   - Set stores a value that Get returns until it expires. Setting an
+//     existing key replaces both its value and its expiration.
+//   - Delete removes a key; deleting a missing key is a no-op.
+//   - A ttl > 0 expires the entry once ttl has elapsed, whether or not the
+//     janitor has run yet.
+//   - NoExpiration (-1) stores an entry that never expires, even in a cache
+//     whose default ttl is short and whose janitor runs often.
+//   - DefaultExpiration (0) applies the default ttl the cache was built with.
+//   - The janitor only removes expired entries.
+//   - All methods are safe for concurrent use.
+//
+// RunLifecycle further checks that Close, or the end of the context a cache
+// was built with, stops its goroutines, and that Close is idempotent.
+package conformance
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// The ttl values every version must give these meanings.
+const (
+	NoExpiration      time.Duration = -1
+	DefaultExpiration time.Duration = 0
+)
+
+const (
+	// shortTTL is the ttl used by the expiration tests. Caches built by the
+	// suite also get it as their default ttl, so their janitors run during
+	// the tests.
+	shortTTL = 50 * time.Millisecond
+
+	// expiryWait is how long the suite waits for a shortTTL entry to
+	// expire, leaving time for a few janitor runs.
+	expiryWait = 4 * shortTTL
+)
+
+// Cache is the method set the suite exercises.
+type Cache interface {
+	Set(key string, value any, ttl time.Duration)
+	Get(key string) (any, bool)
+	Delete(key string)
+}
+
+// Factory builds an empty cache whose default ttl is defaultTTL and whose
+// janitor, if it has one, runs at least every defaultTTL.
+type Factory func(defaultTTL time.Duration) Cache
+
+// Run runs the suite against caches built by newCache.
+func Run(t *testing.T, newCache Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, newCache Factory)
+	}{
+		{"SetGet", testSetGet},
+		{"Overwrite", testOverwrite},
+		{"Delete", testDelete},
+		{"TTL", testTTL},
+		{"NoExpiration", testNoExpiration},
+		{"DefaultExpiration", testDefaultExpiration},
+		{"OverwriteTTL", testOverwriteTTL},
+		{"Concurrent", testConcurrent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			tt.fn(t, newCache)
+		})
+	}
+}
+
+// wantValue fails t unless c holds want under key.
+func wantValue(t *testing.T, c Cache, key string, want any) {
+	t.Helper()
+	got, ok := c.Get(key)
+	if !ok {
+		t.Errorf("Get(%q) missed, want %v", key, want)
+		return
+	}
+	if got != want {
+		t.Errorf("Get(%q) = %v, want %v", key, got, want)
+	}
+}
+
+// wantMiss fails t if c holds key.
+func wantMiss(t *testing.T, c Cache, key string) {
+	t.Helper()
+	if got, ok := c.Get(key); ok {
+		t.Errorf("Get(%q) = %v, want a miss", key, got)
+	}
+}
+
+func testSetGet(t *testing.T, newCache Factory) {
+	c := newCache(time.Minute)
+	wantMiss(t, c, "missing")
+
+	c.Set("string", "value", time.Minute)
+	c.Set("int", 42, NoExpiration)
+	c.Set("default", 3.5, DefaultExpiration)
+	c.Set("", "empty key", time.Minute)
+	wantValue(t, c, "string", "value")
+	wantValue(t, c, "int", 42)
+	wantValue(t, c, "default", 3.5)
+	wantValue(t, c, "", "empty key")
+}
+
+func testOverwrite(t *testing.T, newCache Factory) {
+	c := newCache(time.Minute)
+	c.Set("key", "old", time.Minute)
+	c.Set("key", "new", time.Minute)
+	wantValue(t, c, "key", "new")
+}
+
+func testDelete(t *testing.T, newCache Factory) {
+	c := newCache(time.Minute)
+	c.Set("key", "value", time.Minute)
+	c.Set("other", "value", time.Minute)
+	c.Delete("key")
+	c.Delete("missing")
+	wantMiss(t, c, "key")
+	wantValue(t, c, "other", "value")
+
+	c.Set("key", "again", time.Minute)
+	wantValue(t, c, "key", "again")
+}
+
+func testTTL(t *testing.T, newCache Factory) {
+	c := newCache(time.Minute)
+	c.Set("short", "value", shortTTL)
+	c.Set("long", "value", time.Minute)
+	wantValue(t, c, "short", "value")
+
+	time.Sleep(expiryWait)
+	wantMiss(t, c, "short")
+	wantValue(t, c, "long", "value")
+}
+
+func testNoExpiration(t *testing.T, newCache Factory) {
+	c := newCache(shortTTL)
+	c.Set("forever", "value", NoExpiration)
+	c.Set("short", "value", shortTTL)
+
+	time.Sleep(expiryWait)
+	wantValue(t, c, "forever", "value")
+	wantMiss(t, c, "short")
+}
+
+func testDefaultExpiration(t *testing.T, newCache Factory) {
+	c := newCache(shortTTL)
+	c.Set("default", "value", DefaultExpiration)
+	c.Set("long", "value", time.Minute)
+	wantValue(t, c, "default", "value")
+
+	time.Sleep(expiryWait)
+	wantMiss(t, c, "default")
+	wantValue(t, c, "long", "value")
+}
+
+func testOverwriteTTL(t *testing.T, newCache Factory) {
+	c := newCache(shortTTL)
+	c.Set("extended", "value", shortTTL)
+	c.Set("extended", "value", NoExpiration)
+	c.Set("shortened", "value", NoExpiration)
+	c.Set("shortened", "value", shortTTL)
+
+	time.Sleep(expiryWait)
+	wantValue(t, c, "extended", "value")
+	wantMiss(t, c, "shortened")
+}
+
+func testConcurrent(t *testing.T, newCache Factory) {
+	const (
+		workers = 8
+		keys    = 200
+	)
+	c := newCache(time.Minute)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			prefix := strconv.Itoa(w) + "-"
+			for i := 0; i < keys; i++ {
+				key := prefix + strconv.Itoa(i)
+				c.Set(key, i, time.Minute)
+				c.Get(key)
+				c.Get(strconv.Itoa((w+1)%workers) + "-" + strconv.Itoa(i))
+				if i%2 == 1 {
+					c.Delete(key)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	for w := 0; w < workers; w++ {
+		for i := 0; i < keys; i++ {
+			key := strconv.Itoa(w) + "-" + strconv.Itoa(i)
+			if i%2 == 1 {
+				wantMiss(t, c, key)
+			} else {
+				wantValue(t, c, key, i)
+			}
+		}
+	}
+}
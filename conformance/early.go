@@ -0,0 +1,88 @@
+package conformance
+
+import (
+	"math"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// EarlyCache is the method set RunEarly exercises.
+type EarlyCache interface {
+	Cache
+	SetWithDelta(key string, value any, ttl, delta time.Duration)
+	SetXFetch(beta float64)
+	SetTTLJitter(fraction float64)
+}
+
+// EarlyFactory builds an empty cache like Factory.
+type EarlyFactory func(defaultTTL time.Duration) EarlyCache
+
+// RunEarly checks the early expiration of caches built by newCache: Get
+// misses an item stored with a delta as often as XFetch predicts, never
+// misses items stored without one, and SetTTLJitter spreads the expiries of
+// items stored with the same ttl.
+func RunEarly(t *testing.T, newCache EarlyFactory) {
+	t.Run("XFetch", func(t *testing.T) {
+		t.Parallel()
+		c := newCache(time.Minute)
+		c.SetXFetch(1)
+		c.Set("plain", "value", time.Minute)
+		c.SetWithDelta("cheap", "value", time.Minute, time.Millisecond)
+		// With delta = ttl, a Get right away misses with probability exp(-1).
+		c.SetWithDelta("costly", "value", time.Minute, time.Minute)
+
+		const gets = 2000
+		misses := map[string]int{}
+		for i := 0; i < gets; i++ {
+			for _, key := range []string{"plain", "cheap", "costly"} {
+				if _, ok := c.Get(key); !ok {
+					misses[key]++
+				}
+			}
+		}
+		if misses["plain"] != 0 || misses["cheap"] != 0 {
+			t.Errorf("%d and %d early misses of items without a delta and with a small one, want 0",
+				misses["plain"], misses["cheap"])
+		}
+		if got, want := float64(misses["costly"])/gets, math.Exp(-1); math.Abs(got-want) > 0.06 {
+			t.Errorf("early miss ratio = %.3f, want about %.3f", got, want)
+		}
+		// Early misses leave the item in place for other callers.
+		wantValue(t, c, "plain", "value")
+
+		c.SetXFetch(0)
+		for i := 0; i < 100; i++ {
+			if _, ok := c.Get("costly"); !ok {
+				t.Fatalf("Get missed after SetXFetch(0)")
+			}
+		}
+	})
+
+	t.Run("Jitter", func(t *testing.T) {
+		t.Parallel()
+		const keys, ttl = 200, 4 * shortTTL
+		c := newCache(time.Minute)
+		c.SetTTLJitter(0.5)
+		for i := 0; i < keys; i++ {
+			c.Set(strconv.Itoa(i), i, ttl)
+		}
+		c.Set("forever", "value", NoExpiration)
+
+		time.Sleep(ttl * 2 / 5)
+		for i := 0; i < keys; i++ {
+			wantValue(t, c, strconv.Itoa(i), i)
+		}
+		time.Sleep(ttl * 7 / 20) // At 3/4 of the ttl, about half have expired
+		expired := 0
+		for i := 0; i < keys; i++ {
+			if _, ok := c.Get(strconv.Itoa(i)); !ok {
+				expired++
+			}
+		}
+		if expired < keys/5 || expired > keys*4/5 {
+			t.Errorf("%d of %d items expired at 3/4 of their ttl with 50%% jitter, want about half", expired, keys)
+		}
+		wantValue(t, c, "forever", "value")
+	})
+}
@@ -0,0 +1,158 @@
+package conformance
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"benchmark-gocache/policy"
+)
+
+// EvictCache is the method set RunEvict exercises.
+type EvictCache interface {
+	Cache
+	SetMaxEntries(n int)
+	OnEvict(fn func(key string, value any, reason policy.EvictReason))
+}
+
+// EvictFactory builds an empty cache like Factory.
+type EvictFactory func(defaultTTL time.Duration) EvictCache
+
+// eviction is a call of an OnEvict callback.
+type eviction struct {
+	key    string
+	value  any
+	reason policy.EvictReason
+}
+
+// evictLog records the calls of the OnEvict callback it installs.
+type evictLog struct {
+	mu    sync.Mutex
+	calls []eviction
+}
+
+// watch installs the callback of l on c. Every call reads key back from c,
+// which deadlocks if c calls it with a lock held.
+func (l *evictLog) watch(c EvictCache) {
+	c.OnEvict(func(key string, value any, reason policy.EvictReason) {
+		c.Get(key)
+		l.mu.Lock()
+		l.calls = append(l.calls, eviction{key, value, reason})
+		l.mu.Unlock()
+	})
+}
+
+func (l *evictLog) len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.calls)
+}
+
+// take returns the calls recorded since the last take.
+func (l *evictLog) take() []eviction {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	calls := l.calls
+	l.calls = nil
+	return calls
+}
+
+// want fails t unless the calls since the last take are exactly want. It
+// gives the callback a second to be called, since some versions remove the
+// items Get finds expired in the background.
+func (l *evictLog) want(t *testing.T, want ...eviction) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); l.len() < len(want) && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	got := l.take()
+	if len(got) != len(want) {
+		t.Errorf("OnEvict called with %v, want %v", got, want)
+		return
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("OnEvict called with %v, want %v", got, want)
+			return
+		}
+	}
+}
+
+// RunEvict checks that caches built by newCache report every item they
+// remove to their OnEvict callback, once and with the right reason, and that
+// the callback may call back into the cache.
+func RunEvict(t *testing.T, newCache EvictFactory) {
+	t.Run("Reasons", func(t *testing.T) {
+		t.Parallel()
+		c := newCache(time.Minute)
+		var l evictLog
+		l.watch(c)
+
+		c.Set("key", "old", time.Minute)
+		l.want(t)
+		c.Set("key", "new", time.Minute)
+		l.want(t, eviction{"key", "old", policy.Replaced})
+		c.Delete("key")
+		c.Delete("key")
+		l.want(t, eviction{"key", "new", policy.Deleted})
+
+		c.Set("short", "value", shortTTL)
+		time.Sleep(2 * shortTTL)
+		wantMiss(t, c, "short")
+		l.want(t, eviction{"short", "value", policy.Expired})
+
+		c.OnEvict(nil)
+		c.Set("other", "value", time.Minute)
+		c.Delete("other")
+		l.want(t)
+	})
+
+	t.Run("Janitor", func(t *testing.T) {
+		t.Parallel()
+		c := newCache(shortTTL)
+		var l evictLog
+		l.watch(c)
+		c.Set("short", "value", shortTTL)
+		c.Set("forever", "value", NoExpiration)
+
+		// Whether the janitor or the Get removes it, it is reported once.
+		time.Sleep(expiryWait)
+		wantMiss(t, c, "short")
+		l.want(t, eviction{"short", "value", policy.Expired})
+	})
+
+	t.Run("Capacity", func(t *testing.T) {
+		t.Parallel()
+		const keys = 500
+		c := newCache(time.Minute)
+		var l evictLog
+		l.watch(c)
+		c.SetMaxEntries(50)
+		for i := 0; i < keys; i++ {
+			c.Set(strconv.Itoa(i), i, time.Minute)
+		}
+
+		evicted := map[string]bool{}
+		for _, e := range l.take() {
+			if e.reason != policy.Capacity || e.value != atoi(e.key) || evicted[e.key] {
+				t.Fatalf("unexpected OnEvict call %v", e)
+			}
+			evicted[e.key] = true
+		}
+		for i := 0; i < keys; i++ {
+			key := strconv.Itoa(i)
+			if _, ok := c.Get(key); ok == evicted[key] {
+				t.Fatalf("Get(%q) hit = %v, but evicted = %v", key, ok, evicted[key])
+			}
+		}
+		if len(evicted) < keys/2 {
+			t.Errorf("%d of %d keys evicted from a cache bounded to 50, want most", len(evicted), keys)
+		}
+	})
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
@@ -0,0 +1,73 @@
+package conformance
+
+import (
+	"context"
+	"io"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// CloserFactory builds an empty cache like Factory, with its background
+// goroutines, if any, bound to ctx.
+type CloserFactory func(ctx context.Context, defaultTTL time.Duration) io.Closer
+
+// RunLifecycle checks that caches built by newCache stop every goroutine they
+// start, either on Close or once their context is done, and that Close is
+// idempotent. It counts goroutines, so it must not run in parallel with
+// other tests.
+func RunLifecycle(t *testing.T, newCache CloserFactory) {
+	t.Run("Close", func(t *testing.T) {
+		before := runtime.NumGoroutine()
+		c := newCache(context.Background(), shortTTL)
+		for i := 0; i < 3; i++ {
+			if err := c.Close(); err != nil {
+				t.Fatalf("Close #%d: %v", i+1, err)
+			}
+		}
+		if n := runtime.NumGoroutine(); n > before {
+			t.Errorf("%d goroutines after Close, want at most %d", n, before)
+		}
+	})
+
+	t.Run("Context", func(t *testing.T) {
+		before := runtime.NumGoroutine()
+		ctx, cancel := context.WithCancel(context.Background())
+		c := newCache(ctx, shortTTL)
+		cancel()
+		// The janitor sees the cancellation asynchronously.
+		waitGoroutines(t, before)
+		if err := c.Close(); err != nil {
+			t.Fatalf("Close after cancel: %v", err)
+		}
+	})
+
+	t.Run("ConcurrentClose", func(t *testing.T) {
+		before := runtime.NumGoroutine()
+		c := newCache(context.Background(), shortTTL)
+		errs := make(chan error)
+		for i := 0; i < 4; i++ {
+			go func() { errs <- c.Close() }()
+		}
+		for i := 0; i < 4; i++ {
+			if err := <-errs; err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+		}
+		waitGoroutines(t, before)
+	})
+}
+
+// waitGoroutines fails t unless the goroutine count drops to want within
+// expiryWait.
+func waitGoroutines(t *testing.T, want int) {
+	t.Helper()
+	deadline := time.Now().Add(expiryWait)
+	for runtime.NumGoroutine() > want {
+		if time.Now().After(deadline) {
+			t.Errorf("%d goroutines remain, want at most %d", runtime.NumGoroutine(), want)
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
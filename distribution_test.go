@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	"benchmark-gocache/workload"
+)
+
+var (
+	keyspace = flag.Int("keys", 100000, "keyspace size for the distribution benchmarks")
+	skew     = flag.Float64("skew", workload.DefaultSkew, "Zipf and latest skew for the distribution benchmarks")
+)
+
+// streamLen is the number of pre-generated keys each distribution
+// benchmark cycles through.
+const streamLen = 1 << 20
+
+// distributionStreams pre-generates one key stream per distribution.
+func distributionStreams(b *testing.B) (specs []workload.Spec, streams [][]string) {
+	for _, d := range workload.Distributions {
+		s := workload.Spec{Distribution: d, Keys: *keyspace, Skew: *skew, Seed: 1}
+		g, err := workload.New(s)
+		if err != nil {
+			b.Fatal(err)
+		}
+		specs = append(specs, s)
+		streams = append(streams, workload.Keys(g, streamLen))
+	}
+	return specs, streams
+}
+
+// BenchmarkDistribution replays a cache-aside workload (Get, and Set on a
+// miss) under each key distribution and reports the resulting hit ratio.
+func BenchmarkDistribution(b *testing.B) {
+	specs, streams := distributionStreams(b)
+	for i, s := range specs {
+		keys := streams[i]
+		for _, name := range selectedCaches(b) {
+			b.Run(s.String()+"/"+name, func(b *testing.B) {
+				gc := measureGC(b)
+				c := newCache(b, name)
+				var hits int
+				gc.resetTimer()
+				for i := 0; i < b.N; i++ {
+					key := keys[i&(streamLen-1)]
+					if _, ok := c.Get(key); ok {
+						hits++
+						continue
+					}
+					c.Set(key, value, 10*time.Minute)
+				}
+				gc.report(c)
+				b.ReportMetric(float64(hits)/float64(b.N), "hit-ratio")
+			})
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"runtime"
+	"testing"
+	"time"
+
+	"benchmark-gocache/adapter"
+	"benchmark-gocache/gcstats"
+)
+
+var resident = flag.Int("resident", 1_000_000, "entries kept resident by the Resident benchmarks")
+
+// gcMeter reports the GC cost of a benchmark: allocations per op, GC pause
+// and CPU time per op, GC cycles per million ops and, for caches that
+// report Len, the live heap bytes per entry.
+type gcMeter struct {
+	b     *testing.B
+	heap  uint64 // live heap before the cache was built
+	start gcstats.Snapshot
+}
+
+// measureGC starts a gcMeter. Call it before building the cache, so the
+// cache's own allocations count towards its heap footprint.
+func measureGC(b *testing.B) *gcMeter {
+	b.ReportAllocs()
+	return &gcMeter{b: b, heap: gcstats.LiveHeap()}
+}
+
+// resetTimer resets b's timer and starts counting GC activity. Use it in
+// place of b.ResetTimer.
+func (m *gcMeter) resetTimer() {
+	m.b.ResetTimer()
+	m.start = gcstats.Read()
+}
+
+// report stops b's timer and reports the GC activity since resetTimer and
+// the heap footprint of the entries held by c.
+func (m *gcMeter) report(c adapter.Cache) {
+	m.b.StopTimer()
+	d := gcstats.Read().Sub(m.start)
+	n := float64(m.b.N)
+	m.b.ReportMetric(float64(d.Pause)/n, "gc-pause-ns/op")
+	m.b.ReportMetric(float64(d.CPU)/n, "gc-cpu-ns/op")
+	m.b.ReportMetric(float64(d.Cycles)*1e6/n, "gc-cycles/Mop")
+	if entries := c.Len(); entries > 0 {
+		live := int64(gcstats.LiveHeap()) - int64(m.heap)
+		m.b.ReportMetric(float64(live)/float64(entries), "heap-B/entry")
+	}
+}
+
+// BenchmarkResident keeps -resident entries in each cache and overwrites
+// them, which leaves garbage behind and forces GC cycles that have to mark
+// every resident entry. Besides the gcMeter metrics it reports
+// "full-gc-ns", the time of one forced collection with the entries resident.
+// Keys are allocated before the cache, so caches that keep the key string
+// rather than copying it are not charged for the key bytes.
+func BenchmarkResident(b *testing.B) {
+	keys := keyspaceKeys(*resident)
+	for _, name := range selectedCaches(b) {
+		b.Run(name, func(b *testing.B) {
+			gc := measureGC(b)
+			c := newCache(b, name)
+			drainOnCleanup(b, c, keys)
+			populate(c, keys)
+
+			start := time.Now()
+			runtime.GC()
+			fullGC := time.Since(start)
+
+			gc.resetTimer()
+			for i := 0; i < b.N; i++ {
+				c.Set(keys[i%len(keys)], value, 10*time.Minute)
+			}
+			gc.report(c)
+			b.ReportMetric(float64(fullGC), "full-gc-ns")
+		})
+	}
+}
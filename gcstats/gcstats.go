@@ -0,0 +1,109 @@
+// Package gcstats reads garbage collector activity and heap size from
+// runtime/metrics, so benchmarks can report what a cache costs the GC
+// and not only how fast it is.
+package gcstats
+
+import (
+	"math"
+	"runtime"
+	"runtime/metrics"
+	"time"
+)
+
+const (
+	metricCycles = "/gc/cycles/total:gc-cycles"
+	metricPauses = "/sched/pauses/total/gc:seconds"
+	metricGCCPU  = "/cpu/classes/gc/total:cpu-seconds"
+	metricAllocs = "/gc/heap/allocs:objects"
+	metricAllocB = "/gc/heap/allocs:bytes"
+	metricLive   = "/gc/heap/live:bytes"
+)
+
+// Snapshot holds cumulative GC counters at one point in time. Subtract two
+// snapshots to get the activity between them.
+type Snapshot struct {
+	Cycles     uint64        // completed GC cycles
+	Pause      time.Duration // total stop-the-world GC pause time (estimated from a histogram)
+	CPU        time.Duration // CPU time spent on GC, including assists and background workers
+	Allocs     uint64        // heap objects allocated
+	AllocBytes uint64        // heap bytes allocated
+}
+
+// Read returns the current counters.
+func Read() Snapshot {
+	samples := []metrics.Sample{
+		{Name: metricCycles},
+		{Name: metricPauses},
+		{Name: metricGCCPU},
+		{Name: metricAllocs},
+		{Name: metricAllocB},
+	}
+	metrics.Read(samples)
+	return Snapshot{
+		Cycles:     uint64Value(samples[0]),
+		Pause:      histogramSum(samples[1]),
+		CPU:        time.Duration(float64Value(samples[2]) * float64(time.Second)),
+		Allocs:     uint64Value(samples[3]),
+		AllocBytes: uint64Value(samples[4]),
+	}
+}
+
+// Sub returns the activity between old and s.
+func (s Snapshot) Sub(old Snapshot) Snapshot {
+	return Snapshot{
+		Cycles:     s.Cycles - old.Cycles,
+		Pause:      s.Pause - old.Pause,
+		CPU:        s.CPU - old.CPU,
+		Allocs:     s.Allocs - old.Allocs,
+		AllocBytes: s.AllocBytes - old.AllocBytes,
+	}
+}
+
+// LiveHeap runs a full garbage collection and returns the bytes of heap
+// objects that survived it.
+func LiveHeap() uint64 {
+	runtime.GC()
+	s := []metrics.Sample{{Name: metricLive}}
+	metrics.Read(s)
+	return uint64Value(s[0])
+}
+
+// uint64Value returns s as a uint64, or 0 if the runtime does not support it.
+func uint64Value(s metrics.Sample) uint64 {
+	if s.Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return s.Value.Uint64()
+}
+
+// float64Value returns s as a float64, or 0 if the runtime does not support it.
+func float64Value(s metrics.Sample) float64 {
+	if s.Value.Kind() != metrics.KindFloat64 {
+		return 0
+	}
+	return s.Value.Float64()
+}
+
+// histogramSum estimates the total of a duration histogram by counting
+// every sample at the midpoint of its bucket.
+func histogramSum(s metrics.Sample) time.Duration {
+	if s.Value.Kind() != metrics.KindFloat64Histogram {
+		return 0
+	}
+	h := s.Value.Float64Histogram()
+	var sum float64
+	for i, n := range h.Counts {
+		if n == 0 {
+			continue
+		}
+		lo, hi := h.Buckets[i], h.Buckets[i+1]
+		switch {
+		case math.IsInf(lo, -1):
+			lo = hi
+		case math.IsInf(hi, 1):
+			hi = lo
+		}
+		sum += float64(n) * (lo + hi) / 2
+	}
+	return time.Duration(sum * float64(time.Second))
+}
@@ -0,0 +1,41 @@
+package gcstats
+
+import (
+	"runtime"
+	"testing"
+)
+
+var sink [][]byte
+
+func TestRead_CountsCyclesAndAllocs(t *testing.T) {
+	before := Read()
+	for i := 0; i < 1000; i++ {
+		sink = append(sink, make([]byte, 1024))
+	}
+	runtime.GC()
+	d := Read().Sub(before)
+	sink = nil
+
+	if d.Cycles == 0 {
+		t.Error("Cycles did not advance after runtime.GC")
+	}
+	if d.Allocs < 1000 || d.AllocBytes < 1000*1024 {
+		t.Errorf("Allocs = %d, AllocBytes = %d, want at least 1000 and %d", d.Allocs, d.AllocBytes, 1000*1024)
+	}
+	if d.Pause < 0 || d.CPU < 0 {
+		t.Errorf("Pause = %v, CPU = %v, want non-negative", d.Pause, d.CPU)
+	}
+}
+
+func TestLiveHeap_TracksRetainedObjects(t *testing.T) {
+	const size = 32 << 20
+	before := LiveHeap()
+	buf := make([]byte, size)
+	after := LiveHeap()
+	runtime.KeepAlive(buf)
+
+	// Allow for unrelated objects freed in between.
+	if after < before+size*9/10 {
+		t.Errorf("LiveHeap() grew from %d to %d, want about %d more", before, after, size)
+	}
+}
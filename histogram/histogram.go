@@ -0,0 +1,125 @@
+// Package histogram records latencies in an HDR-style log-linear histogram.
+//
+// Values below 256ns are counted exactly. Larger values fall into buckets
+// that split every power of two into 128 linear steps, so a reported
+// quantile is never more than 1/128 (under 1%) above the recorded value.
+// Memory is fixed, whatever the number of samples, which lets benchmarks
+// record every operation without storing or sorting the samples.
+package histogram
+
+import (
+	"math"
+	"math/bits"
+	"time"
+)
+
+const (
+	subBits    = 7
+	subCount   = 1 << subBits // linear steps per power of two
+	numBuckets = (64 - subBits) * subCount
+)
+
+// Histogram counts durations. The zero value is empty and ready to use.
+// A Histogram is not safe for concurrent use; record into one per
+// goroutine and Merge them.
+type Histogram struct {
+	counts [numBuckets]uint64
+	total  uint64
+	min    int64
+	max    int64
+}
+
+// bucket returns the index of the bucket holding v.
+func bucket(v int64) int {
+	if v < 2*subCount {
+		return int(v)
+	}
+	shift := bits.Len64(uint64(v)) - subBits - 1
+	top := int(v >> shift) // in [subCount, 2*subCount)
+	return (shift+1)*subCount + top - subCount
+}
+
+// highest returns the largest value that falls into bucket i.
+func highest(i int) int64 {
+	if i < 2*subCount {
+		return int64(i)
+	}
+	shift := i/subCount - 1
+	top := int64(i%subCount + subCount)
+	return (top+1)<<shift - 1
+}
+
+// Record adds d to the histogram. Negative durations count as zero.
+func (h *Histogram) Record(d time.Duration) {
+	v := int64(d)
+	if v < 0 {
+		v = 0
+	}
+	h.counts[bucket(v)]++
+	if h.total == 0 || v < h.min {
+		h.min = v
+	}
+	if v > h.max {
+		h.max = v
+	}
+	h.total++
+}
+
+// Count returns the number of recorded durations.
+func (h *Histogram) Count() uint64 { return h.total }
+
+// Min returns the smallest recorded duration.
+func (h *Histogram) Min() time.Duration { return time.Duration(h.min) }
+
+// Max returns the largest recorded duration.
+func (h *Histogram) Max() time.Duration { return time.Duration(h.max) }
+
+// Quantile returns the duration below or at which a fraction q of the
+// recorded durations fall, for q in [0, 1]. It returns 0 when empty.
+func (h *Histogram) Quantile(q float64) time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	if q <= 0 {
+		return h.Min()
+	}
+	if q >= 1 {
+		return h.Max()
+	}
+	rank := uint64(math.Ceil(q * float64(h.total)))
+	var seen uint64
+	for i, n := range h.counts {
+		seen += n
+		if seen >= rank {
+			v := highest(i)
+			if v > h.max {
+				v = h.max
+			}
+			if v < h.min {
+				v = h.min
+			}
+			return time.Duration(v)
+		}
+	}
+	return h.Max()
+}
+
+// Merge adds every duration recorded in o to h.
+func (h *Histogram) Merge(o *Histogram) {
+	if o.total == 0 {
+		return
+	}
+	for i, n := range o.counts {
+		h.counts[i] += n
+	}
+	if h.total == 0 || o.min < h.min {
+		h.min = o.min
+	}
+	if o.max > h.max {
+		h.max = o.max
+	}
+	h.total += o.total
+}
+
+// Reset empties the histogram.
+func (h *Histogram) Reset() { *h = Histogram{} }
@@ -0,0 +1,97 @@
+package histogram
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestHistogram_Empty(t *testing.T) {
+	var h Histogram
+	if h.Count() != 0 || h.Quantile(0.5) != 0 || h.Max() != 0 {
+		t.Errorf("empty histogram: Count=%d Quantile(0.5)=%v Max=%v", h.Count(), h.Quantile(0.5), h.Max())
+	}
+}
+
+func TestHistogram_SmallValuesExact(t *testing.T) {
+	var h Histogram
+	for v := 1; v <= 100; v++ {
+		h.Record(time.Duration(v))
+	}
+	tests := []struct {
+		q    float64
+		want time.Duration
+	}{
+		{0, 1}, {0.01, 1}, {0.5, 50}, {0.9, 90}, {0.99, 99}, {1, 100},
+	}
+	for _, tt := range tests {
+		if got := h.Quantile(tt.q); got != tt.want {
+			t.Errorf("Quantile(%v) = %v, want %v", tt.q, got, tt.want)
+		}
+	}
+	if h.Count() != 100 || h.Min() != 1 || h.Max() != 100 {
+		t.Errorf("Count=%d Min=%v Max=%v, want 100, 1, 100", h.Count(), h.Min(), h.Max())
+	}
+}
+
+func TestHistogram_RelativeError(t *testing.T) {
+	var h Histogram
+	r := rand.New(rand.NewSource(1))
+	samples := make([]time.Duration, 100000)
+	for i := range samples {
+		// Log-uniform between 1ns and about 1s.
+		samples[i] = time.Duration(math.Exp2(r.Float64() * 30))
+		h.Record(samples[i])
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+
+	for _, q := range []float64{0.5, 0.9, 0.99, 0.999} {
+		want := samples[int(math.Ceil(q*float64(len(samples))))-1]
+		got := h.Quantile(q)
+		if got < want || float64(got-want) > float64(want)/subCount {
+			t.Errorf("Quantile(%v) = %v, want within 1/%d above %v", q, got, subCount, want)
+		}
+	}
+	if h.Max() != samples[len(samples)-1] {
+		t.Errorf("Max() = %v, want %v", h.Max(), samples[len(samples)-1])
+	}
+}
+
+func TestHistogram_Huge(t *testing.T) {
+	var h Histogram
+	h.Record(time.Duration(1<<63 - 1))
+	h.Record(-time.Second)
+	if h.Max() != time.Duration(1<<63-1) || h.Min() != 0 {
+		t.Errorf("Min=%v Max=%v", h.Min(), h.Max())
+	}
+}
+
+func TestHistogram_Merge(t *testing.T) {
+	var a, b, all Histogram
+	for v := 1; v <= 1000; v++ {
+		d := time.Duration(v) * time.Microsecond
+		if v%2 == 0 {
+			a.Record(d)
+		} else {
+			b.Record(d)
+		}
+		all.Record(d)
+	}
+	a.Merge(&b)
+	if a.Count() != all.Count() || a.Min() != all.Min() || a.Max() != all.Max() {
+		t.Fatalf("merged Count/Min/Max = %d/%v/%v, want %d/%v/%v",
+			a.Count(), a.Min(), a.Max(), all.Count(), all.Min(), all.Max())
+	}
+	for _, q := range []float64{0.5, 0.99} {
+		if a.Quantile(q) != all.Quantile(q) {
+			t.Errorf("merged Quantile(%v) = %v, want %v", q, a.Quantile(q), all.Quantile(q))
+		}
+	}
+
+	a.Reset()
+	if a.Count() != 0 {
+		t.Errorf("Count() after Reset = %d", a.Count())
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"testing"
+	"time"
+
+	"benchmark-gocache/adapter"
+)
+
+var (
+	capacity    = flag.Int("capacity", 10000, "entry budget for the HitRatio benchmarks")
+	budgetBytes = flag.Int64("budget-bytes", 1<<20, "byte budget for the HitRatio benchmarks")
+	valueSize   = flag.Int("value-size", 64, "value size in bytes for the HitRatio benchmarks")
+)
+
+// newBoundedCache builds the named cache with cfg, skipping the benchmark
+// when the cache cannot honour the budget.
+func newBoundedCache(b *testing.B, name string, cfg adapter.Config) adapter.Cache {
+	c, err := adapter.New(name, cfg)
+	if errors.Is(err, adapter.ErrBudgetUnsupported) {
+		b.Skip(err)
+	}
+	if err != nil {
+		b.Fatalf("New(%q) error: %v", name, err)
+	}
+	closeOnCleanup(b, c)
+	return c
+}
+
+// BenchmarkHitRatio gives every cache the same entry budget (-capacity) and
+// byte budget (-budget-bytes), replays a cache-aside workload under each key
+// distribution over -keys keys, and reports the hit ratio next to ns/op.
+// Caches that cannot honour a budget are skipped.
+func BenchmarkHitRatio(b *testing.B) {
+	budgets := []struct {
+		name string
+		cfg  adapter.Config
+	}{
+		{name: fmt.Sprintf("entries-%d", *capacity), cfg: adapter.Config{MaxEntries: *capacity}},
+		{name: fmt.Sprintf("bytes-%d", *budgetBytes), cfg: adapter.Config{MaxBytes: *budgetBytes}},
+	}
+	val := make([]byte, *valueSize)
+
+	specs, streams := distributionStreams(b)
+	for _, budget := range budgets {
+		for i, s := range specs {
+			keys := streams[i]
+			for _, name := range selectedCaches(b) {
+				b.Run(budget.name+"/"+s.String()+"/"+name, func(b *testing.B) {
+					gc := measureGC(b)
+					c := newBoundedCache(b, name, budget.cfg)
+					var hits int
+					gc.resetTimer()
+					for i := 0; i < b.N; i++ {
+						key := keys[i&(streamLen-1)]
+						if _, ok := c.Get(key); ok {
+							hits++
+							continue
+						}
+						c.Set(key, val, 10*time.Minute)
+					}
+					gc.report(c)
+					b.ReportMetric(float64(hits)/float64(b.N), "hit-ratio")
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	"benchmark-gocache/adapter"
+	"benchmark-gocache/histogram"
+)
+
+var cleanupInterval = flag.Duration("cleanup-interval", 10*time.Millisecond,
+	"janitor interval for the Latency/cleanup benchmarks")
+
+// latencyScenarios are the cache configurations the Latency benchmarks run
+// under. In "cleanup" the janitor fires every -cleanup-interval and has to
+// scan the whole pre-populated keyspace, so stalls behind its locks show up
+// in the tail percentiles. Entries are written with a long ttl, so the
+// keyspace stays the same size in both scenarios.
+var latencyScenarios = []struct {
+	name string
+	cfg  func() adapter.Config
+}{
+	{name: "steady", cfg: func() adapter.Config { return adapter.Config{} }},
+	{name: "cleanup", cfg: func() adapter.Config {
+		return adapter.Config{TTL: *cleanupInterval, CleanupInterval: *cleanupInterval}
+	}},
+}
+
+// latencyOps are the operations timed by BenchmarkLatency. Each runs
+// against a cache holding keys and times only the call being measured.
+var latencyOps = []struct {
+	name string
+	op   func(c adapter.Cache, key string, h *histogram.Histogram)
+}{
+	{name: "set", op: func(c adapter.Cache, key string, h *histogram.Histogram) {
+		start := time.Now()
+		c.Set(key, value, 10*time.Minute)
+		h.Record(time.Since(start))
+	}},
+	{name: "get", op: func(c adapter.Cache, key string, h *histogram.Histogram) {
+		start := time.Now()
+		c.Get(key)
+		h.Record(time.Since(start))
+	}},
+	{name: "delete", op: func(c adapter.Cache, key string, h *histogram.Histogram) {
+		start := time.Now()
+		c.Delete(key)
+		h.Record(time.Since(start))
+		// Put the key back so later iterations delete a present key.
+		c.Set(key, value, 10*time.Minute)
+	}},
+}
+
+// reportLatency reports the percentiles recorded in h as benchmark metrics.
+func reportLatency(b *testing.B, h *histogram.Histogram) {
+	b.ReportMetric(float64(h.Quantile(0.50)), "p50-ns")
+	b.ReportMetric(float64(h.Quantile(0.90)), "p90-ns")
+	b.ReportMetric(float64(h.Quantile(0.99)), "p99-ns")
+	b.ReportMetric(float64(h.Quantile(0.999)), "p999-ns")
+	b.ReportMetric(float64(h.Max()), "max-ns")
+}
+
+// BenchmarkLatency times every Set, Get and Delete individually over a
+// pre-populated keyspace of -keys keys and reports p50/p90/p99/p99.9/max
+// latency next to ns/op. The delete ns/op also includes re-inserting the
+// key; the percentiles cover the Delete call alone.
+func BenchmarkLatency(b *testing.B) {
+	keys := keyspaceKeys(*keyspace)
+	for _, sc := range latencyScenarios {
+		for _, name := range selectedCaches(b) {
+			for _, lo := range latencyOps {
+				b.Run(sc.name+"/"+name+"/"+lo.name, func(b *testing.B) {
+					gc := measureGC(b)
+					c, err := adapter.New(name, sc.cfg())
+					if err != nil {
+						b.Fatalf("New(%q) error: %v", name, err)
+					}
+					closeOnCleanup(b, c)
+					drainOnCleanup(b, c, keys)
+					populate(c, keys)
+
+					var h histogram.Histogram
+					gc.resetTimer()
+					for i := 0; i < b.N; i++ {
+						lo.op(c, keys[i%len(keys)], &h)
+					}
+					gc.report(c)
+					reportLatency(b, &h)
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,202 @@
+// Package loader implements GetOrLoad for the sharded gocache versions (v5,
+// v9 and v11): on a miss, concurrent callers asking for the same key share a
+// single call of the loader, as with singleflight, and the value it returns
+// is stored in the cache before any of them sees it.
+//
+// Group.Refresh reloads a key in the background instead, for caches that serve
+// a stale value while it is revalidated.
+//
+// A loader error is returned to every caller waiting on that load and is not
+// stored in the cache. If the loader returns a ttl > 0 along with the error,
+// the error is remembered for ttl instead, and GetOrLoad returns it without
+// calling the loader again until it expires, or until a Set stores a value.
+package loader
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Func loads the value of a key missing from the cache, and the ttl to store
+// it with, as passed to Set. With a non-nil error, a ttl > 0 is how long
+// to remember the error; otherwise the error is not remembered.
+type Func func(ctx context.Context) (value any, ttl time.Duration, err error)
+
+// Cache is the part of a cache that a Group loads values into.
+type Cache interface {
+	Get(key string) (any, bool)
+	Set(key string, value any, ttl time.Duration)
+}
+
+// deltaCache is implemented by the caches that can expire items early with
+// XFetch. Loaded values are stored with the time their load took.
+type deltaCache interface {
+	SetWithDelta(key string, value any, ttl, delta time.Duration)
+}
+
+// Group coalesces the loads of each key. The zero value is ready to use.
+// A cache embeds one Group and hands itself to Load.
+type Group struct {
+	mu     sync.Mutex
+	calls  map[string]*call   // Loads in flight
+	errors map[string]failure // Remembered loader errors
+	purge  int                // Size of errors that triggers a purge of the expired ones
+}
+
+// call is a load in flight.
+type call struct {
+	done    chan struct{} // Closed once value and err are set
+	value   any
+	err     error
+	waiters int                // Callers still waiting for the result
+	cancel  context.CancelFunc // Cancels the context of the loader
+}
+
+// failure is a remembered loader error.
+type failure struct {
+	err     error
+	expires int64
+}
+
+// Load returns the value c holds under key or, on a miss, loads it with fn
+// and stores it in c. Callers loading the same key at the same time share a
+// single call of fn and its result.
+//
+// fn runs with a context that keeps the values of ctx, but is only canceled
+// once every caller waiting on it has given up. A caller whose ctx is done
+// before the load returns gets ctx.Err(); the load goes on for the others.
+func (g *Group) Load(ctx context.Context, c Cache, key string, fn Func) (any, error) {
+	if v, ok := c.Get(key); ok {
+		return v, nil
+	}
+
+	g.mu.Lock()
+	if f, ok := g.errors[key]; ok {
+		if time.Now().UnixNano() <= f.expires {
+			g.mu.Unlock()
+			return nil, f.err
+		}
+		delete(g.errors, key)
+	}
+	cl, ok := g.calls[key]
+	if !ok {
+		if g.calls == nil {
+			g.calls = make(map[string]*call)
+		}
+		loadCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		cl = &call{done: make(chan struct{}), cancel: cancel}
+		g.calls[key] = cl
+		go g.run(loadCtx, c, key, fn, cl, false)
+	}
+	cl.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-cl.done:
+		return cl.value, cl.err
+	case <-ctx.Done():
+	}
+
+	g.mu.Lock()
+	if cl.waiters--; cl.waiters == 0 {
+		cl.cancel()
+		if g.calls[key] == cl {
+			delete(g.calls, key)
+		}
+	}
+	g.mu.Unlock()
+	return nil, ctx.Err()
+}
+
+// Refresh starts loading key with fn in the background and reports whether
+// it did. Unlike Load, it reloads values c already holds, and it does not wait
+// for the result. It does nothing if a load of key is already in flight, or
+// if a loader error for key is still remembered.
+// Callers of Load may share the refresh; it is not canceled when they give up.
+func (g *Group) Refresh(c Cache, key string, fn Func) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if f, ok := g.errors[key]; ok && time.Now().UnixNano() <= f.expires {
+		return false
+	}
+	if _, ok := g.calls[key]; ok {
+		return false
+	}
+	if g.calls == nil {
+		g.calls = make(map[string]*call)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	// The refresh counts as a waiter that never gives up.
+	cl := &call{done: make(chan struct{}), cancel: cancel, waiters: 1}
+	g.calls[key] = cl
+	go g.run(ctx, c, key, fn, cl, true)
+	return true
+}
+
+// run calls fn for the load cl of key, stores its result and wakes up the
+// callers waiting on cl. Unless reload is set, it first looks for key in c.
+func (g *Group) run(ctx context.Context, c Cache, key string, fn Func, cl *call, reload bool) {
+	defer cl.cancel()
+
+	// A load that finished between our caller's miss and the creation of
+	// cl has already stored the value.
+	var hit bool
+	if !reload {
+		cl.value, hit = c.Get(key)
+	}
+	if !hit {
+		var ttl time.Duration
+		start := time.Now()
+		cl.value, ttl, cl.err = safeLoad(ctx, key, fn)
+		if dc, ok := c.(deltaCache); ok && cl.err == nil {
+			dc.SetWithDelta(key, cl.value, ttl, time.Since(start))
+		} else if cl.err == nil {
+			c.Set(key, cl.value, ttl)
+		} else if ttl > 0 {
+			g.remember(key, cl.err, ttl)
+		}
+	}
+
+	g.mu.Lock()
+	if g.calls[key] == cl {
+		delete(g.calls, key)
+	}
+	g.mu.Unlock()
+	close(cl.done)
+}
+
+// safeLoad calls fn, turning a panic into an error so that it reaches the
+// waiting callers instead of crashing the loading goroutine.
+func safeLoad(ctx context.Context, key string, fn Func) (value any, ttl time.Duration, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			value, ttl, err = nil, 0, fmt.Errorf("loader: load of %q panicked: %v", key, r)
+		}
+	}()
+	return fn(ctx)
+}
+
+// remember records err as the result of loading key for the next ttl.
+// Expired errors of other keys are purged whenever the map has doubled in
+// size since the last purge.
+func (g *Group) remember(key string, err error, ttl time.Duration) {
+	now := time.Now().UnixNano()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.errors == nil {
+		g.errors = make(map[string]failure)
+	}
+	if len(g.errors) >= g.purge {
+		for k, f := range g.errors {
+			if now > f.expires {
+				delete(g.errors, k)
+			}
+		}
+		g.purge = max(2*len(g.errors), 64)
+	}
+	g.errors[key] = failure{err: err, expires: now + int64(ttl)}
+}
@@ -0,0 +1,234 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// mapCache is a Cache that ignores ttls.
+type mapCache struct {
+	mu    sync.Mutex
+	items map[string]any
+	sets  int
+}
+
+func newMapCache() *mapCache {
+	return &mapCache{items: make(map[string]any)}
+}
+
+func (c *mapCache) Get(key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.items[key]
+	return v, ok
+}
+
+func (c *mapCache) Set(key string, value any, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.items[key] = value
+	c.sets++
+}
+
+func TestGroup_Coalesces(t *testing.T) {
+	var g Group
+	c := newMapCache()
+	var calls atomic.Int32
+	release := make(chan struct{})
+	load := func(ctx context.Context) (any, time.Duration, error) {
+		calls.Add(1)
+		<-release
+		return "value", time.Minute, nil
+	}
+
+	const callers = 50
+	var wg sync.WaitGroup
+	errs := make(chan error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := g.Load(context.Background(), c, "key", load)
+			if err == nil && v != "value" {
+				err = errors.New("wrong value")
+			}
+			errs <- err
+		}()
+	}
+	time.Sleep(50 * time.Millisecond) // Let every caller join the load
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Load() error: %v", err)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("loader called %d times, want 1", n)
+	}
+	if c.sets != 1 {
+		t.Errorf("Set called %d times, want 1", c.sets)
+	}
+	if v, err := g.Load(context.Background(), c, "key", load); err != nil || v != "value" {
+		t.Errorf("Load() after the load = %v, %v; want value, nil", v, err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("loader called %d times on a hit, want 1", n)
+	}
+}
+
+func TestGroup_Errors(t *testing.T) {
+	var g Group
+	c := newMapCache()
+	errBackend := errors.New("backend down")
+	var calls atomic.Int32
+	fail := func(ttl time.Duration) Func {
+		return func(ctx context.Context) (any, time.Duration, error) {
+			calls.Add(1)
+			return nil, ttl, errBackend
+		}
+	}
+
+	// Without a ttl, errors are not remembered.
+	for i := 1; i <= 2; i++ {
+		if _, err := g.Load(context.Background(), c, "key", fail(0)); err != errBackend {
+			t.Fatalf("Load() error = %v, want %v", err, errBackend)
+		}
+		if n := calls.Load(); n != int32(i) {
+			t.Fatalf("loader called %d times, want %d", n, i)
+		}
+	}
+	if _, ok := c.Get("key"); ok {
+		t.Fatalf("a failed load stored a value")
+	}
+
+	// With one, they are returned without calling the loader until they expire.
+	calls.Store(0)
+	for i := 0; i < 3; i++ {
+		if _, err := g.Load(context.Background(), c, "neg", fail(100*time.Millisecond)); err != errBackend {
+			t.Fatalf("Load() error = %v, want %v", err, errBackend)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("loader called %d times within the error ttl, want 1", n)
+	}
+	time.Sleep(150 * time.Millisecond)
+	g.Load(context.Background(), c, "neg", fail(0))
+	if n := calls.Load(); n != 2 {
+		t.Errorf("loader called %d times after the error ttl, want 2", n)
+	}
+
+	// A Set takes precedence over a remembered error.
+	g.Load(context.Background(), c, "set", fail(time.Minute))
+	c.Set("set", "value", 0)
+	if v, err := g.Load(context.Background(), c, "set", fail(time.Minute)); err != nil || v != "value" {
+		t.Errorf("Load() after Set = %v, %v; want value, nil", v, err)
+	}
+}
+
+func TestGroup_Panic(t *testing.T) {
+	var g Group
+	_, err := g.Load(context.Background(), newMapCache(), "key", func(ctx context.Context) (any, time.Duration, error) {
+		panic("boom")
+	})
+	if err == nil {
+		t.Fatal("Load() of a panicking loader succeeded")
+	}
+}
+
+func TestGroup_Cancel(t *testing.T) {
+	var g Group
+	c := newMapCache()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	canceled := make(chan struct{})
+	load := func(ctx context.Context) (any, time.Duration, error) {
+		close(started)
+		select {
+		case <-release:
+			return "value", time.Minute, nil
+		case <-ctx.Done():
+			close(canceled)
+			return nil, 0, ctx.Err()
+		}
+	}
+
+	// A caller giving up does not cancel the load while another waits on it.
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := g.Load(ctx, c, "key", load)
+		done <- err
+	}()
+	<-started
+	result := make(chan any)
+	go func() {
+		v, _ := g.Load(context.Background(), c, "key", load)
+		result <- v
+	}()
+	time.Sleep(20 * time.Millisecond) // Let the second caller join the load
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("Load() with a canceled ctx error = %v, want %v", err, context.Canceled)
+	}
+	close(release)
+	if v := <-result; v != "value" {
+		t.Fatalf("Load() of the remaining caller = %v, want value", v)
+	}
+
+	// Once the last caller gives up, the load is canceled.
+	started, release, canceled = make(chan struct{}), make(chan struct{}), make(chan struct{})
+	ctx, cancel = context.WithCancel(context.Background())
+	go g.Load(ctx, c, "other", load)
+	<-started
+	cancel()
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("the load was not canceled after its only caller gave up")
+	}
+}
+
+func TestGroup_Refresh(t *testing.T) {
+	var g Group
+	c := newMapCache()
+	c.Set("key", "old", 0)
+	release := make(chan struct{})
+	load := func(ctx context.Context) (any, time.Duration, error) {
+		<-release
+		return "new", time.Minute, nil
+	}
+
+	if !g.Refresh(c, "key", load) {
+		t.Fatal("Refresh() of a cached key did not start a load")
+	}
+	if g.Refresh(c, "key", load) {
+		t.Error("Refresh() started a second load of the same key")
+	}
+	if v, err := g.Load(context.Background(), c, "key", load); err != nil || v != "old" {
+		t.Errorf("Load() during a refresh = %v, %v; want old, nil", v, err)
+	}
+	close(release)
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+		if v, _ := c.Get("key"); v == "new" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the refresh did not store its value")
+		}
+	}
+
+	// Remembered errors suspend refreshes.
+	g.Load(context.Background(), c, "failing", func(ctx context.Context) (any, time.Duration, error) {
+		return nil, time.Minute, errors.New("backend down")
+	})
+	if g.Refresh(c, "failing", load) {
+		t.Error("Refresh() started a load despite a remembered error")
+	}
+}
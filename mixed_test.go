@@ -0,0 +1,103 @@
+package main
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	"benchmark-gocache/adapter"
+	"benchmark-gocache/workload"
+)
+
+var (
+	mixes = flag.String("mixes", "90/10,50/50,99/1,80/10/10",
+		"comma-separated read/write[/delete] ratios for the Mixed benchmarks")
+	dist = flag.String("dist", string(workload.Uniform),
+		"key distribution for the Mixed benchmarks: uniform, zipf, hotspot, latest or scan")
+)
+
+// keyspaceKeys returns the names of every key in a keyspace of n keys.
+func keyspaceKeys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = workload.KeyName(uint64(i))
+	}
+	return keys
+}
+
+// forEachMix pre-generates streamLen operations for every mix in -mixes,
+// drawing keys from -dist over -keys, and calls fn with each stream.
+func forEachMix(b *testing.B, fn func(m workload.Mix, ops []workload.Op)) {
+	ms, err := workload.ParseMixes(*mixes)
+	if err != nil {
+		b.Fatal(err)
+	}
+	d, err := workload.ParseDistribution(*dist)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, m := range ms {
+		g, err := workload.New(workload.Spec{Distribution: d, Keys: *keyspace, Skew: *skew, Seed: 1})
+		if err != nil {
+			b.Fatal(err)
+		}
+		fn(m, workload.Ops(m, g, streamLen, 1))
+	}
+}
+
+// apply runs op against c and reports whether a Get hit.
+func apply(c adapter.Cache, op workload.Op) bool {
+	switch op.Kind {
+	case workload.OpGet:
+		_, ok := c.Get(op.Key)
+		return ok
+	case workload.OpSet:
+		c.Set(op.Key, value, 10*time.Minute)
+	case workload.OpDelete:
+		c.Delete(op.Key)
+	}
+	return false
+}
+
+// BenchmarkMixed runs each -mixes ratio over a pre-populated keyspace and
+// reports throughput per operation type and the Get hit ratio.
+// Every operation is timed individually to attribute time to its type, which
+// adds the cost of two clock reads to ns/op.
+func BenchmarkMixed(b *testing.B) {
+	keys := keyspaceKeys(*keyspace)
+	forEachMix(b, func(m workload.Mix, ops []workload.Op) {
+		for _, name := range selectedCaches(b) {
+			b.Run(m.String()+"/"+name, func(b *testing.B) {
+				gc := measureGC(b)
+				c := newCache(b, name)
+				populate(c, keys)
+
+				var (
+					n    [len(workload.OpKinds)]int
+					dur  [len(workload.OpKinds)]time.Duration
+					hits int
+				)
+				gc.resetTimer()
+				for i := 0; i < b.N; i++ {
+					op := ops[i&(streamLen-1)]
+					start := time.Now()
+					if apply(c, op) {
+						hits++
+					}
+					dur[op.Kind] += time.Since(start)
+					n[op.Kind]++
+				}
+				gc.report(c)
+
+				for _, k := range workload.OpKinds {
+					if n[k] > 0 && dur[k] > 0 {
+						b.ReportMetric(float64(n[k])/dur[k].Seconds(), k.String()+"-ops/s")
+					}
+				}
+				if g := n[workload.OpGet]; g > 0 {
+					b.ReportMetric(float64(hits)/float64(g), "hit-ratio")
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"benchmark-gocache/adapter"
+	"benchmark-gocache/workload"
+)
+
+var parallelism = flag.String("parallelism", "1,4,16",
+	"comma-separated b.SetParallelism values for the Parallel benchmarks; each runs value×GOMAXPROCS goroutines")
+
+const (
+	// parallelKeys is the size of the pre-populated keyspace read by the
+	// Get and Mixed parallel benchmarks. It must be a power of two.
+	parallelKeys = 1 << 16
+	parallelMask = parallelKeys - 1
+
+	// goroutineStride spreads the starting key of each goroutine so they
+	// do not walk the keyspace in lockstep.
+	goroutineStride = 7919
+)
+
+// parallelKeyspace holds the keys used by the parallel benchmarks,
+// generated once outside the timed loops.
+var parallelKeyspace = keyspaceKeys(parallelKeys)
+
+// parallelisms parses -parallelism.
+func parallelisms(b *testing.B) []int {
+	var ps []int
+	for _, f := range strings.Split(*parallelism, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		p, err := strconv.Atoi(f)
+		if err != nil || p < 1 {
+			b.Fatalf("invalid -parallelism value %q", f)
+		}
+		ps = append(ps, p)
+	}
+	return ps
+}
+
+// benchParallel runs fn as a sub-benchmark for each selected cache and
+// parallelism, with b.SetParallelism already applied. fn starts the timer
+// with gc.resetTimer; benchParallel reports the GC cost once fn returns.
+func benchParallel(b *testing.B, fn func(b *testing.B, c adapter.Cache, gc *gcMeter)) {
+	for _, name := range selectedCaches(b) {
+		for _, p := range parallelisms(b) {
+			b.Run(fmt.Sprintf("%s/p%d", name, p), func(b *testing.B) {
+				gc := measureGC(b)
+				c := newCache(b, name)
+				b.SetParallelism(p)
+				fn(b, c, gc)
+				gc.report(c)
+			})
+		}
+	}
+}
+
+// populate stores every key of keys in c.
+func populate(c adapter.Cache, keys []string) {
+	for _, key := range keys {
+		c.Set(key, value, 10*time.Minute)
+	}
+	adapter.Wait(c)
+}
+
+// BenchmarkParallelSet measures concurrent Sets, each goroutine writing its own keys.
+func BenchmarkParallelSet(b *testing.B) {
+	benchParallel(b, func(b *testing.B, c adapter.Cache, gc *gcMeter) {
+		var id atomic.Uint64
+		gc.resetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			prefix := strconv.FormatUint(id.Add(1), 10) + "-"
+			for i := 0; pb.Next(); i++ {
+				c.Set(prefix+strconv.Itoa(i), value, time.Minute)
+			}
+		})
+	})
+}
+
+// BenchmarkParallelGet measures concurrent Gets over a pre-populated keyspace.
+func BenchmarkParallelGet(b *testing.B) {
+	benchParallel(b, func(b *testing.B, c adapter.Cache, gc *gcMeter) {
+		populate(c, parallelKeyspace)
+		var id, misses atomic.Uint64
+		gc.resetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			i := id.Add(1) * goroutineStride
+			var miss uint64
+			for ; pb.Next(); i++ {
+				if _, ok := c.Get(parallelKeyspace[i&parallelMask]); !ok {
+					miss++
+				}
+			}
+			misses.Add(miss)
+		})
+		b.ReportMetric(float64(misses.Load())/float64(b.N), "misses/op")
+	})
+}
+
+// BenchmarkParallelMixed runs each -mixes ratio concurrently over a
+// pre-populated keyspace of -keys keys.
+func BenchmarkParallelMixed(b *testing.B) {
+	keys := keyspaceKeys(*keyspace)
+	forEachMix(b, func(m workload.Mix, ops []workload.Op) {
+		b.Run(m.String(), func(b *testing.B) {
+			benchParallel(b, func(b *testing.B, c adapter.Cache, gc *gcMeter) {
+				populate(c, keys)
+				var id, gets, hits atomic.Uint64
+				gc.resetTimer()
+				b.RunParallel(func(pb *testing.PB) {
+					i := id.Add(1) * goroutineStride
+					var g, h uint64
+					for ; pb.Next(); i++ {
+						op := ops[i&(streamLen-1)]
+						if op.Kind == workload.OpGet {
+							g++
+						}
+						if apply(c, op) {
+							h++
+						}
+					}
+					gets.Add(g)
+					hits.Add(h)
+				})
+				if g := gets.Load(); g > 0 {
+					b.ReportMetric(float64(hits.Load())/float64(g), "hit-ratio")
+				}
+			})
+		})
+	})
+}
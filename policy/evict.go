@@ -0,0 +1,83 @@
+package policy
+
+// EvictReason tells an OnEvict callback why the cache removed an item.
+type EvictReason int
+
+const (
+	// Expired items outlived their ttl. The cleanup goroutine, or a Get that
+	// found them expired, removed them.
+	Expired EvictReason = iota + 1
+	// Deleted items were removed by Delete.
+	Deleted
+	// Replaced items were overwritten by a Set of their key.
+	Replaced
+	// Capacity items were evicted to keep the cache within its bounds.
+	Capacity
+)
+
+func (r EvictReason) String() string {
+	switch r {
+	case Expired:
+		return "expired"
+	case Deleted:
+		return "deleted"
+	case Replaced:
+		return "replaced"
+	case Capacity:
+		return "capacity"
+	}
+	return "unknown"
+}
+
+// Evictions buffers the items a cache shard removes while locked, so that the
+// cache reports them to the OnEvict callback after unlocking, and the callback
+// may call back into the cache. Like policies, it is only used with the shard
+// locked. The zero value has no callback and records nothing.
+type Evictions struct {
+	fn      func(key string, value any, reason EvictReason)
+	pending []eviction
+}
+
+type eviction struct {
+	key    string
+	value  any
+	reason EvictReason
+}
+
+// SetFunc sets the callback the items recorded from now on are reported to;
+// nil stops recording.
+func (e *Evictions) SetFunc(fn func(key string, value any, reason EvictReason)) {
+	e.fn = fn
+}
+
+// Add records that the cache removed key, which held value, for reason.
+func (e *Evictions) Add(key string, value any, reason EvictReason) {
+	if e.fn != nil {
+		e.pending = append(e.pending, eviction{key, value, reason})
+	}
+}
+
+// Take returns the items recorded since the last Take, to be reported once
+// the shard is unlocked.
+func (e *Evictions) Take() EvictionBatch {
+	if len(e.pending) == 0 {
+		return EvictionBatch{}
+	}
+	b := EvictionBatch{fn: e.fn, items: e.pending}
+	e.pending = nil
+	return b
+}
+
+// EvictionBatch is a list of removed items returned by Evictions.Take.
+type EvictionBatch struct {
+	fn    func(key string, value any, reason EvictReason)
+	items []eviction
+}
+
+// Report calls the callback with each item of b, in the order they were
+// removed.
+func (b EvictionBatch) Report() {
+	for _, it := range b.items {
+		b.fn(it.key, it.value, it.reason)
+	}
+}
@@ -0,0 +1,99 @@
+package policy
+
+import "container/heap"
+
+// lfuEntry is a key tracked by an lfu policy.
+type lfuEntry struct {
+	key   string
+	freq  uint64 // Insert and accesses
+	seq   uint64 // Value of lfu.seq at the last of them
+	index int    // Position in the heap
+}
+
+// lfuHeap orders entries by frequency, the least recently used first among
+// equally frequent ones.
+type lfuHeap []*lfuEntry
+
+func (h lfuHeap) Len() int { return len(h) }
+
+func (h lfuHeap) Less(i, j int) bool {
+	if h[i].freq != h[j].freq {
+		return h[i].freq < h[j].freq
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h lfuHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *lfuHeap) Push(x any) {
+	e := x.(*lfuEntry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *lfuHeap) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return e
+}
+
+// lfu evicts the least frequently used key. Frequencies never decay, so keys
+// that were popular once can outlive a shift in popularity.
+type lfu struct {
+	heap    lfuHeap
+	entries map[string]*lfuEntry
+	seq     uint64 // Number of inserts and accesses so far
+}
+
+// NewLFU returns a policy that evicts the least frequently used key, breaking
+// ties by recency.
+func NewLFU(capacity int) EvictionPolicy {
+	return &lfu{
+		heap:    make(lfuHeap, 0, capacity),
+		entries: make(map[string]*lfuEntry, capacity),
+	}
+}
+
+func (p *lfu) OnInsert(key string) {
+	if _, ok := p.entries[key]; ok {
+		p.OnAccess(key)
+		return
+	}
+	p.seq++
+	e := &lfuEntry{key: key, freq: 1, seq: p.seq}
+	p.entries[key] = e
+	heap.Push(&p.heap, e)
+}
+
+func (p *lfu) OnAccess(key string) {
+	e, ok := p.entries[key]
+	if !ok {
+		return
+	}
+	p.seq++
+	e.freq++
+	e.seq = p.seq
+	heap.Fix(&p.heap, e.index)
+}
+
+func (p *lfu) OnDelete(key string) {
+	if e, ok := p.entries[key]; ok {
+		heap.Remove(&p.heap, e.index)
+		delete(p.entries, key)
+	}
+}
+
+func (p *lfu) Victim() (string, bool) {
+	if len(p.heap) == 0 {
+		return "", false
+	}
+	e := heap.Pop(&p.heap).(*lfuEntry)
+	delete(p.entries, e.key)
+	return e.key, true
+}
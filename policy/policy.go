@@ -0,0 +1,57 @@
+// Package policy defines EvictionPolicy, the interface through which the
+// bounded sharded gocache versions (v5, v9 and v11) choose which key to evict,
+// and ships the classic policies: LRU, LFU, FIFO, random, S3-FIFO and SIEVE.
+//
+// A cache builds one policy per shard, sized for the shard's share of the
+// entry budget, and only calls it with the shard locked, so implementations
+// need not be safe for concurrent use. Policies only order keys; the cache
+// stores the items and removes the keys its policy chooses.
+//
+// The package also defines EvictReason and Evictions, with which the sharded
+// versions (v5 to v11) report every item they remove to an OnEvict callback.
+package policy
+
+// EvictionPolicy tracks the keys stored in one cache shard and picks the next
+// one to evict.
+type EvictionPolicy interface {
+	// OnInsert starts tracking key, which the cache just stored.
+	OnInsert(key string)
+
+	// OnAccess records a hit on key, or a Set that replaced its value.
+	// Keys that are not tracked are ignored.
+	OnAccess(key string)
+
+	// OnDelete stops tracking key, which the cache removed on its own, as
+	// on a Delete or on expiry. Keys that are not tracked are ignored.
+	OnDelete(key string)
+
+	// Victim chooses the key to evict, stops tracking it and returns it.
+	// It reports false if no key is tracked.
+	Victim() (key string, ok bool)
+}
+
+// Factory builds a policy for a shard that holds at most capacity keys.
+type Factory func(capacity int) EvictionPolicy
+
+// names lists the shipped policies in the order benchmarks report them.
+var names = []string{"lru", "lfu", "fifo", "random", "s3-fifo", "sieve"}
+
+var factories = map[string]Factory{
+	"lru":     NewLRU,
+	"lfu":     NewLFU,
+	"fifo":    NewFIFO,
+	"random":  NewRandom,
+	"s3-fifo": NewS3FIFO,
+	"sieve":   NewSIEVE,
+}
+
+// Names returns the names of the shipped policies.
+func Names() []string {
+	return append([]string(nil), names...)
+}
+
+// Lookup returns the factory of the shipped policy called name.
+func Lookup(name string) (Factory, bool) {
+	f, ok := factories[name]
+	return f, ok
+}
@@ -0,0 +1,190 @@
+package policy
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+// victims drains p and returns its victims in order.
+func victims(p EvictionPolicy) []string {
+	var keys []string
+	for {
+		key, ok := p.Victim()
+		if !ok {
+			return keys
+		}
+		keys = append(keys, key)
+	}
+}
+
+func TestPolicies_Contract(t *testing.T) {
+	for _, name := range Names() {
+		t.Run(name, func(t *testing.T) {
+			newPolicy, ok := Lookup(name)
+			if !ok {
+				t.Fatalf("Lookup(%q) failed", name)
+			}
+			p := newPolicy(100)
+			if key, ok := p.Victim(); ok {
+				t.Fatalf("Victim() on an empty policy = %q, true", key)
+			}
+
+			// Unknown keys are ignored.
+			p.OnAccess("missing")
+			p.OnDelete("missing")
+
+			var want []string
+			for i := 0; i < 100; i++ {
+				key := strconv.Itoa(i)
+				p.OnInsert(key)
+				if i%3 == 0 {
+					p.OnAccess(key)
+				}
+				if i%10 == 0 {
+					p.OnDelete(key)
+					continue
+				}
+				want = append(want, key)
+			}
+			p.OnDelete("0") // Deleting twice is a no-op
+
+			got := victims(p)
+			sort.Strings(got)
+			sort.Strings(want)
+			if len(got) != len(want) {
+				t.Fatalf("got %d victims, want %d", len(got), len(want))
+			}
+			for i := range got {
+				if got[i] != want[i] {
+					t.Fatalf("victims = %v, want every tracked key once: %v", got, want)
+				}
+			}
+			p.OnAccess("1") // Victims are no longer tracked
+			if key, ok := p.Victim(); ok {
+				t.Errorf("Victim() after draining = %q, true", key)
+			}
+		})
+	}
+}
+
+func TestLookup_Unknown(t *testing.T) {
+	if _, ok := Lookup("clock"); ok {
+		t.Errorf("Lookup(clock) succeeded")
+	}
+}
+
+// insert inserts keys into p in order.
+func insert(p EvictionPolicy, keys ...string) {
+	for _, key := range keys {
+		p.OnInsert(key)
+	}
+}
+
+// expectVictim fails t unless p's next victim is want.
+func expectVictim(t *testing.T, p EvictionPolicy, want string) {
+	t.Helper()
+	if got, ok := p.Victim(); !ok || got != want {
+		t.Errorf("Victim() = %q, %v; want %q", got, ok, want)
+	}
+}
+
+func TestLRU(t *testing.T) {
+	p := NewLRU(3)
+	insert(p, "a", "b", "c")
+	p.OnAccess("a")
+	expectVictim(t, p, "b")
+	expectVictim(t, p, "c")
+	expectVictim(t, p, "a")
+}
+
+func TestFIFO(t *testing.T) {
+	p := NewFIFO(3)
+	insert(p, "a", "b", "c")
+	p.OnAccess("a")
+	expectVictim(t, p, "a")
+	expectVictim(t, p, "b")
+}
+
+func TestLFU(t *testing.T) {
+	p := NewLFU(3)
+	insert(p, "a", "b", "c")
+	p.OnAccess("a")
+	p.OnAccess("a")
+	p.OnAccess("c")
+	expectVictim(t, p, "b")
+	p.OnAccess("c") // c is now more frequent than a
+	p.OnAccess("c")
+	expectVictim(t, p, "a")
+}
+
+func TestSIEVE(t *testing.T) {
+	p := NewSIEVE(4)
+	insert(p, "a", "b", "c", "d")
+	p.OnAccess("a")
+	p.OnAccess("c")
+	// The hand clears a, evicts b, and resumes after it.
+	expectVictim(t, p, "b")
+	p.OnInsert("e")
+	p.OnAccess("a")
+	expectVictim(t, p, "d")
+	expectVictim(t, p, "e")
+	// Back at the oldest key, the hand clears a again and evicts c, whose
+	// bit it cleared on its first pass.
+	expectVictim(t, p, "c")
+	expectVictim(t, p, "a")
+}
+
+func TestS3FIFO(t *testing.T) {
+	p := NewS3FIFO(10) // A small queue of one key, a ghost queue of nine
+	insert(p, "hot")
+	p.OnAccess("hot")
+	for i := 0; i < 9; i++ {
+		p.OnInsert(strconv.Itoa(i))
+	}
+
+	// hot was accessed in the small queue, so it moves to main; the keys
+	// seen once are evicted in insertion order while the small queue is over
+	// its share.
+	expectVictim(t, p, "0")
+	expectVictim(t, p, "1")
+
+	// A key inserted again soon after its eviction goes straight to main.
+	p.OnInsert("0")
+	if e := p.(*s3fifo).entries["0"]; e == nil || !e.main {
+		t.Errorf("re-inserted key 0 is not in the main queue")
+	}
+
+	for _, key := range victims(p)[:7] {
+		if key == "hot" || key == "0" {
+			t.Errorf("key %q evicted before the keys seen once", key)
+		}
+	}
+}
+
+func TestEvictions(t *testing.T) {
+	var e Evictions
+	e.Add("ignored", 0, Deleted)
+	if b := e.Take(); len(b.items) != 0 {
+		t.Fatalf("Take() without a callback = %d items, want 0", len(b.items))
+	}
+
+	var got []string
+	e.SetFunc(func(key string, value any, reason EvictReason) {
+		got = append(got, key+"="+reason.String())
+	})
+	e.Add("a", 1, Replaced)
+	e.Add("b", 2, Capacity)
+	b := e.Take()
+	e.Add("c", 3, Expired)
+	b.Report()
+	if want := []string{"a=replaced", "b=capacity"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Report() called back with %v, want %v", got, want)
+	}
+	got = nil
+	e.Take().Report()
+	if want := []string{"c=expired"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Report() called back with %v, want %v", got, want)
+	}
+}
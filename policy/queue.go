@@ -0,0 +1,64 @@
+package policy
+
+import "container/list"
+
+// queue evicts keys in the order they entered it. An LRU queue moves a key
+// back to the front on every access; a FIFO queue ignores accesses.
+type queue struct {
+	order  *list.List               // Keys, newest first
+	elems  map[string]*list.Element // Element of each key in order
+	recent bool                     // Whether accesses move keys to the front
+}
+
+// NewLRU returns a policy that evicts the least recently used key.
+func NewLRU(capacity int) EvictionPolicy {
+	return newQueue(capacity, true)
+}
+
+// NewFIFO returns a policy that evicts the oldest key, however often it has
+// been accessed since.
+func NewFIFO(capacity int) EvictionPolicy {
+	return newQueue(capacity, false)
+}
+
+func newQueue(capacity int, recent bool) *queue {
+	return &queue{
+		order:  list.New(),
+		elems:  make(map[string]*list.Element, capacity),
+		recent: recent,
+	}
+}
+
+func (q *queue) OnInsert(key string) {
+	if e, ok := q.elems[key]; ok {
+		q.order.MoveToFront(e)
+		return
+	}
+	q.elems[key] = q.order.PushFront(key)
+}
+
+func (q *queue) OnAccess(key string) {
+	if !q.recent {
+		return
+	}
+	if e, ok := q.elems[key]; ok {
+		q.order.MoveToFront(e)
+	}
+}
+
+func (q *queue) OnDelete(key string) {
+	if e, ok := q.elems[key]; ok {
+		q.order.Remove(e)
+		delete(q.elems, key)
+	}
+}
+
+func (q *queue) Victim() (string, bool) {
+	e := q.order.Back()
+	if e == nil {
+		return "", false
+	}
+	key := q.order.Remove(e).(string)
+	delete(q.elems, key)
+	return key, true
+}
@@ -0,0 +1,52 @@
+package policy
+
+import "math/rand"
+
+// random evicts a uniformly chosen key. It keeps keys in a slice, so that it
+// can pick one in constant time, and the index of each key in that slice.
+type random struct {
+	keys  []string
+	index map[string]int
+	rng   *rand.Rand
+}
+
+// NewRandom returns a policy that evicts a key chosen at random, the baseline
+// every other policy should beat.
+func NewRandom(capacity int) EvictionPolicy {
+	return &random{
+		keys:  make([]string, 0, capacity),
+		index: make(map[string]int, capacity),
+		rng:   rand.New(rand.NewSource(rand.Int63())),
+	}
+}
+
+func (p *random) OnInsert(key string) {
+	if _, ok := p.index[key]; ok {
+		return
+	}
+	p.index[key] = len(p.keys)
+	p.keys = append(p.keys, key)
+}
+
+func (p *random) OnAccess(string) {}
+
+func (p *random) OnDelete(key string) {
+	i, ok := p.index[key]
+	if !ok {
+		return
+	}
+	last := len(p.keys) - 1
+	p.keys[i] = p.keys[last]
+	p.index[p.keys[i]] = i
+	p.keys = p.keys[:last]
+	delete(p.index, key)
+}
+
+func (p *random) Victim() (string, bool) {
+	if len(p.keys) == 0 {
+		return "", false
+	}
+	key := p.keys[p.rng.Intn(len(p.keys))]
+	p.OnDelete(key)
+	return key, true
+}
@@ -0,0 +1,126 @@
+package policy
+
+import "container/list"
+
+const (
+	// s3SmallPercent is the share of capacity given to the small queue.
+	s3SmallPercent = 10
+
+	// s3MaxFreq caps the access count of an S3-FIFO entry.
+	s3MaxFreq = 3
+)
+
+// s3Entry is a key tracked by an s3fifo policy.
+type s3Entry struct {
+	key  string
+	freq uint8         // Accesses since insertion, or since the last pass through main
+	main bool          // Whether elem is in the main queue rather than the small one
+	elem *list.Element // Element of the entry in its queue
+}
+
+// s3fifo implements S3-FIFO (Yang et al., SOSP 2023). New keys enter a small
+// FIFO queue, which filters out the many keys accessed only once. A key
+// accessed while in it is promoted to the main FIFO queue on reaching its end;
+// any other is evicted and remembered by a ghost queue of keys only, so that
+// it goes straight to the main queue if inserted again soon. The main queue
+// reinserts keys accessed since their last pass, as a CLOCK does.
+type s3fifo struct {
+	small, main *list.List // Entries, newest first
+	entries     map[string]*s3Entry
+	smallCap    int // Length from which the small queue evicts first
+
+	ghost    *list.List               // Keys recently evicted from the small queue, newest first
+	ghosts   map[string]*list.Element // Element of each key in ghost
+	ghostCap int                      // Maximum length of ghost
+}
+
+// NewS3FIFO returns a policy that evicts with S3-FIFO, which keeps hit ratios
+// close to W-TinyLFU's at the cost of a FIFO queue.
+func NewS3FIFO(capacity int) EvictionPolicy {
+	smallCap := max(1, capacity*s3SmallPercent/100)
+	return &s3fifo{
+		small:    list.New(),
+		main:     list.New(),
+		entries:  make(map[string]*s3Entry, capacity),
+		smallCap: smallCap,
+		ghost:    list.New(),
+		ghosts:   make(map[string]*list.Element),
+		ghostCap: max(1, capacity-smallCap),
+	}
+}
+
+func (p *s3fifo) OnInsert(key string) {
+	if _, ok := p.entries[key]; ok {
+		p.OnAccess(key)
+		return
+	}
+	e := &s3Entry{key: key}
+	if g, ok := p.ghosts[key]; ok {
+		p.ghost.Remove(g)
+		delete(p.ghosts, key)
+		e.main = true
+		e.elem = p.main.PushFront(e)
+	} else {
+		e.elem = p.small.PushFront(e)
+	}
+	p.entries[key] = e
+}
+
+func (p *s3fifo) OnAccess(key string) {
+	if e, ok := p.entries[key]; ok && e.freq < s3MaxFreq {
+		e.freq++
+	}
+}
+
+func (p *s3fifo) OnDelete(key string) {
+	e, ok := p.entries[key]
+	if !ok {
+		return
+	}
+	p.queue(e).Remove(e.elem)
+	delete(p.entries, key)
+}
+
+func (p *s3fifo) Victim() (string, bool) {
+	for p.small.Len() > 0 || p.main.Len() > 0 {
+		if p.small.Len() > 0 && (p.small.Len() >= p.smallCap || p.main.Len() == 0) {
+			e := p.small.Remove(p.small.Back()).(*s3Entry)
+			if e.freq > 0 {
+				e.freq, e.main = 0, true
+				e.elem = p.main.PushFront(e)
+				continue
+			}
+			delete(p.entries, e.key)
+			p.remember(e.key)
+			return e.key, true
+		}
+
+		back := p.main.Back()
+		e := back.Value.(*s3Entry)
+		if e.freq > 0 {
+			e.freq--
+			p.main.MoveToFront(back)
+			continue
+		}
+		p.main.Remove(back)
+		delete(p.entries, e.key)
+		return e.key, true
+	}
+	return "", false
+}
+
+// queue returns the queue holding e.
+func (p *s3fifo) queue(e *s3Entry) *list.List {
+	if e.main {
+		return p.main
+	}
+	return p.small
+}
+
+// remember adds key to the ghost queue, forgetting the oldest key if it is full.
+func (p *s3fifo) remember(key string) {
+	p.ghosts[key] = p.ghost.PushFront(key)
+	if p.ghost.Len() > p.ghostCap {
+		delete(p.ghosts, p.ghost.Remove(p.ghost.Back()).(string))
+	}
+}
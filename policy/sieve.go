@@ -0,0 +1,75 @@
+package policy
+
+import "container/list"
+
+// sieveEntry is a key tracked by a sieve policy.
+type sieveEntry struct {
+	key     string
+	visited bool // Accessed since the hand last passed it
+}
+
+// sieve implements SIEVE (Zhang et al., NSDI 2024). Keys stay in insertion
+// order and a hand moves from the oldest towards the newest, clearing the
+// visited bit of the keys it passes and evicting the first one whose bit is
+// already clear. Unlike a CLOCK, survivors are not moved, so new keys that are
+// never accessed again are evicted quickly.
+type sieve struct {
+	order *list.List               // Entries, newest first
+	elems map[string]*list.Element // Element of each key in order
+	hand  *list.Element            // Next entry to examine; nil starts from the oldest
+}
+
+// NewSIEVE returns a policy that evicts with SIEVE, which needs no more than a
+// bit per key and no list update on access.
+func NewSIEVE(capacity int) EvictionPolicy {
+	return &sieve{order: list.New(), elems: make(map[string]*list.Element, capacity)}
+}
+
+func (p *sieve) OnInsert(key string) {
+	if _, ok := p.elems[key]; ok {
+		p.OnAccess(key)
+		return
+	}
+	p.elems[key] = p.order.PushFront(&sieveEntry{key: key})
+}
+
+func (p *sieve) OnAccess(key string) {
+	if e, ok := p.elems[key]; ok {
+		e.Value.(*sieveEntry).visited = true
+	}
+}
+
+func (p *sieve) OnDelete(key string) {
+	if e, ok := p.elems[key]; ok {
+		p.remove(e)
+	}
+}
+
+func (p *sieve) Victim() (string, bool) {
+	e := p.hand
+	if e == nil {
+		e = p.order.Back()
+	}
+	if e == nil {
+		return "", false
+	}
+	for entry := e.Value.(*sieveEntry); entry.visited; entry = e.Value.(*sieveEntry) {
+		entry.visited = false
+		if e = e.Prev(); e == nil {
+			e = p.order.Back()
+		}
+	}
+	key := e.Value.(*sieveEntry).key
+	p.hand = e
+	p.remove(e)
+	return key, true
+}
+
+// remove drops e, moving the hand to the next newer entry if it points at e.
+func (p *sieve) remove(e *list.Element) {
+	if p.hand == e {
+		p.hand = e.Prev()
+	}
+	delete(p.elems, e.Value.(*sieveEntry).key)
+	p.order.Remove(e)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"flag"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"benchmark-gocache/adapter"
+	"benchmark-gocache/policy"
+)
+
+var policies = flag.String("policies", "", "comma-separated eviction policies for the Policy benchmarks (default: all)")
+
+// selectedPolicies returns the policy names chosen with -policies.
+func selectedPolicies(b *testing.B) []string {
+	if strings.TrimSpace(*policies) == "" {
+		return policy.Names()
+	}
+	var names []string
+	for _, name := range strings.Split(*policies, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := policy.Lookup(name); !ok {
+			b.Fatalf("unknown policy %q; known: %v", name, policy.Names())
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// benchPolicies runs fn as a sub-benchmark for every key distribution,
+// selected policy and selected cache, with c bounded to -capacity entries by
+// that policy. Caches that cannot take a policy are skipped.
+func benchPolicies(b *testing.B, fn func(b *testing.B, c adapter.Cache, keys []string, gc *gcMeter)) {
+	specs, streams := distributionStreams(b)
+	for i, s := range specs {
+		keys := streams[i]
+		for _, p := range selectedPolicies(b) {
+			newPolicy, _ := policy.Lookup(p)
+			for _, name := range selectedCaches(b) {
+				b.Run(s.String()+"/"+p+"/"+name, func(b *testing.B) {
+					gc := measureGC(b)
+					c := newBoundedCache(b, name, adapter.Config{MaxEntries: *capacity, Policy: newPolicy})
+					fn(b, c, keys, gc)
+					gc.report(c)
+				})
+			}
+		}
+	}
+}
+
+// BenchmarkPolicy replays the cache-aside workload of BenchmarkHitRatio
+// against the versions that take an eviction policy (v5, v9 and v11), once
+// per policy, and reports the hit ratio next to ns/op.
+func BenchmarkPolicy(b *testing.B) {
+	benchPolicies(b, func(b *testing.B, c adapter.Cache, keys []string, gc *gcMeter) {
+		var hits int
+		gc.resetTimer()
+		for i := 0; i < b.N; i++ {
+			key := keys[i&(streamLen-1)]
+			if _, ok := c.Get(key); ok {
+				hits++
+				continue
+			}
+			c.Set(key, value, 10*time.Minute)
+		}
+		b.ReportMetric(float64(hits)/float64(b.N), "hit-ratio")
+	})
+}
+
+// BenchmarkPolicyParallel is BenchmarkPolicy with GOMAXPROCS goroutines
+// replaying the stream from different offsets, which shows how much each
+// policy's bookkeeping on Get costs under contention.
+func BenchmarkPolicyParallel(b *testing.B) {
+	benchPolicies(b, func(b *testing.B, c adapter.Cache, keys []string, gc *gcMeter) {
+		var hits atomic.Int64
+		var id atomic.Uint64
+		gc.resetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			var n int64
+			i := int(id.Add(1)) * goroutineStride
+			for ; pb.Next(); i++ {
+				key := keys[i&(streamLen-1)]
+				if _, ok := c.Get(key); ok {
+					n++
+					continue
+				}
+				c.Set(key, value, 10*time.Minute)
+			}
+			hits.Add(n)
+		})
+		b.ReportMetric(float64(hits.Load())/float64(b.N), "hit-ratio")
+	})
+}
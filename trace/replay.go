@@ -0,0 +1,142 @@
+package trace
+
+import (
+	"time"
+
+	"benchmark-gocache/adapter"
+	"benchmark-gocache/histogram"
+	"benchmark-gocache/workload"
+)
+
+// DefaultValueSize is the value size used for records that do not carry one.
+const DefaultValueSize = 64
+
+// Options control a replay.
+type Options struct {
+	// FillOnMiss sets a key after a Get misses, as a cache-aside client
+	// would. It should be enabled for key-only formats.
+	FillOnMiss bool
+
+	// ValueSize is the size of values written for records without a
+	// ValueSize. Zero means DefaultValueSize.
+	ValueSize int
+
+	// TTL is used for records without a TTL, and for the fills they
+	// trigger. Zero stores entries without expiration.
+	TTL time.Duration
+
+	// Realtime waits between records to honour their timestamps
+	// instead of replaying as fast as possible.
+	Realtime bool
+}
+
+// Latency summarises the latency of one operation type.
+type Latency struct {
+	P50, P90, P99, P999, Max time.Duration
+}
+
+// Result is the outcome of replaying a trace against one cache.
+type Result struct {
+	Ops     [len(workload.OpKinds)]int     // operations replayed, by kind
+	Latency [len(workload.OpKinds)]Latency // latency, by kind
+	Hits    int                            // Gets that found their key
+	Fills   int                            // Sets issued by FillOnMiss
+	Elapsed time.Duration                  // wall time of the replay
+}
+
+// Total returns the number of operations replayed, excluding fills.
+func (r Result) Total() int {
+	n := 0
+	for _, ops := range r.Ops {
+		n += ops
+	}
+	return n
+}
+
+// HitRatio returns the fraction of Gets that hit.
+func (r Result) HitRatio() float64 {
+	if r.Ops[workload.OpGet] == 0 {
+		return 0
+	}
+	return float64(r.Hits) / float64(r.Ops[workload.OpGet])
+}
+
+// Throughput returns replayed operations per second.
+func (r Result) Throughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Total()) / r.Elapsed.Seconds()
+}
+
+// Replay runs recs against c in order and measures hit ratio, throughput
+// and per-operation latency. The latency of a Get includes its fill.
+func Replay(c adapter.Cache, recs []Record, opts Options) Result {
+	size := opts.ValueSize
+	if size <= 0 {
+		size = DefaultValueSize
+	}
+	maxSize := size
+	for i := range recs {
+		if recs[i].ValueSize > maxSize {
+			maxSize = recs[i].ValueSize
+		}
+	}
+	buf := make([]byte, maxSize)
+
+	var (
+		res       Result
+		latencies [len(workload.OpKinds)]histogram.Histogram
+		prevTS    int64
+	)
+	start := time.Now()
+	for i, rec := range recs {
+		if opts.Realtime && i > 0 && rec.Timestamp > prevTS {
+			time.Sleep(time.Duration(rec.Timestamp-prevTS) * time.Millisecond)
+		}
+		prevTS = rec.Timestamp
+
+		val := buf[:size]
+		if rec.ValueSize > 0 {
+			val = buf[:rec.ValueSize]
+		}
+		ttl := opts.TTL
+		if rec.HasTTL {
+			ttl = rec.TTL
+		}
+
+		opStart := time.Now()
+		switch rec.Op {
+		case workload.OpGet:
+			if _, ok := c.Get(rec.Key); ok {
+				res.Hits++
+			} else if opts.FillOnMiss {
+				c.Set(rec.Key, val, ttl)
+				res.Fills++
+			}
+		case workload.OpSet:
+			c.Set(rec.Key, val, ttl)
+		case workload.OpDelete:
+			c.Delete(rec.Key)
+		}
+		latencies[rec.Op].Record(time.Since(opStart))
+		res.Ops[rec.Op]++
+	}
+	res.Elapsed = time.Since(start)
+
+	for k := range latencies {
+		res.Latency[k] = summarize(&latencies[k])
+	}
+	return res
+}
+
+// summarize returns the percentiles recorded in h.
+func summarize(h *histogram.Histogram) Latency {
+	return Latency{
+		P50:  h.Quantile(0.50),
+		P90:  h.Quantile(0.90),
+		P99:  h.Quantile(0.99),
+		P999: h.Quantile(0.999),
+		Max:  h.Max(),
+	}
+}
@@ -0,0 +1,234 @@
+// Package trace reads recorded cache access traces and replays them against
+// any adapter.Cache, so implementations can be compared on captured
+// production traffic instead of synthetic keys.
+//
+// Three formats are supported:
+//
+//   - "csv": one operation per line as op,key,value_size,ttl,timestamp.
+//     op is get, set or delete; value_size is in bytes; ttl is a Go duration
+//     ("10m") or a number of seconds, and 0 stores the key without expiry;
+//     timestamp is in milliseconds (only the differences between records
+//     matter). Every field after key is optional and a header line starting
+//     with "op" is skipped.
+//   - "lirs": one key per line, as in the LIRS and many ARC-derived traces.
+//   - "arc": the ARC paper traces, "start count ignored request" per line,
+//     where each line references the count blocks starting at start.
+//
+// Key-only formats (lirs, arc) carry no writes, so they are replayed as
+// Gets, with a Set of the missing key after every miss.
+package trace
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"benchmark-gocache/workload"
+)
+
+// Format names a trace file format.
+type Format string
+
+const (
+	CSV  Format = "csv"
+	LIRS Format = "lirs"
+	ARC  Format = "arc"
+)
+
+// Record is a single traced cache operation.
+type Record struct {
+	Op        workload.OpKind
+	Key       string
+	ValueSize int           // 0 means the replay default
+	TTL       time.Duration // <= 0 means no expiration, if HasTTL
+	HasTTL    bool          // false if the trace gave no ttl: the replay default applies
+	Timestamp int64         // milliseconds
+}
+
+// Reader returns trace records one at a time, and io.EOF after the last one.
+type Reader interface {
+	Read() (Record, error)
+}
+
+// ParseFormat returns the Format named s.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case CSV, LIRS, ARC:
+		return f, nil
+	}
+	return "", fmt.Errorf("trace: unknown format %q", s)
+}
+
+// KeyOnly reports whether f records only the keys that were read.
+func (f Format) KeyOnly() bool { return f == LIRS || f == ARC }
+
+// NewReader returns a Reader decoding r in format f.
+func NewReader(f Format, r io.Reader) (Reader, error) {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 1024*1024)
+	switch f {
+	case CSV:
+		return &csvReader{s: s}, nil
+	case LIRS:
+		return &lirsReader{s: s}, nil
+	case ARC:
+		return &arcReader{s: s}, nil
+	}
+	return nil, fmt.Errorf("trace: unknown format %q", f)
+}
+
+// ReadAll reads every remaining record from r.
+func ReadAll(r Reader) ([]Record, error) {
+	var recs []Record
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return recs, nil
+		}
+		if err != nil {
+			return recs, err
+		}
+		recs = append(recs, rec)
+	}
+}
+
+// nextLine returns the next non-blank, non-comment line and its 1-based number.
+func nextLine(s *bufio.Scanner, line *int) (string, error) {
+	for s.Scan() {
+		*line++
+		text := strings.TrimSpace(s.Text())
+		if text == "" || text[0] == '#' {
+			continue
+		}
+		return text, nil
+	}
+	if err := s.Err(); err != nil {
+		return "", err
+	}
+	return "", io.EOF
+}
+
+type csvReader struct {
+	s       *bufio.Scanner
+	line    int
+	started bool
+}
+
+func (r *csvReader) Read() (Record, error) {
+	for {
+		text, err := nextLine(r.s, &r.line)
+		if err != nil {
+			return Record{}, err
+		}
+		fields := strings.Split(text, ",")
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+		first := !r.started
+		r.started = true
+		if first && strings.EqualFold(fields[0], "op") {
+			continue // header
+		}
+		rec, err := parseCSV(fields)
+		if err != nil {
+			return Record{}, fmt.Errorf("trace: line %d: %w", r.line, err)
+		}
+		return rec, nil
+	}
+}
+
+func parseCSV(fields []string) (Record, error) {
+	var rec Record
+	if len(fields) < 2 || fields[1] == "" {
+		return rec, fmt.Errorf("want op,key[,value_size,ttl,timestamp], got %d fields", len(fields))
+	}
+
+	switch strings.ToLower(fields[0]) {
+	case "get", "read":
+		rec.Op = workload.OpGet
+	case "set", "write":
+		rec.Op = workload.OpSet
+	case "delete", "del":
+		rec.Op = workload.OpDelete
+	default:
+		return rec, fmt.Errorf("unknown op %q", fields[0])
+	}
+	rec.Key = fields[1]
+
+	var err error
+	if len(fields) > 2 && fields[2] != "" {
+		if rec.ValueSize, err = strconv.Atoi(fields[2]); err != nil || rec.ValueSize < 0 {
+			return rec, fmt.Errorf("invalid value_size %q", fields[2])
+		}
+	}
+	if len(fields) > 3 && fields[3] != "" {
+		if rec.TTL, err = parseTTL(fields[3]); err != nil {
+			return rec, err
+		}
+		rec.HasTTL = true
+	}
+	if len(fields) > 4 && fields[4] != "" {
+		if rec.Timestamp, err = strconv.ParseInt(fields[4], 10, 64); err != nil {
+			return rec, fmt.Errorf("invalid timestamp %q", fields[4])
+		}
+	}
+	return rec, nil
+}
+
+// parseTTL accepts a Go duration or a number of seconds.
+func parseTTL(s string) (time.Duration, error) {
+	if secs, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(secs * float64(time.Second)), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid ttl %q", s)
+	}
+	return d, nil
+}
+
+type lirsReader struct {
+	s    *bufio.Scanner
+	line int
+}
+
+func (r *lirsReader) Read() (Record, error) {
+	text, err := nextLine(r.s, &r.line)
+	if err != nil {
+		return Record{}, err
+	}
+	return Record{Op: workload.OpGet, Key: strings.Fields(text)[0]}, nil
+}
+
+type arcReader struct {
+	s    *bufio.Scanner
+	line int
+
+	next, left uint64 // blocks still to emit from the current line
+}
+
+func (r *arcReader) Read() (Record, error) {
+	for r.left == 0 {
+		text, err := nextLine(r.s, &r.line)
+		if err != nil {
+			return Record{}, err
+		}
+		fields := strings.Fields(text)
+		if len(fields) < 2 {
+			return Record{}, fmt.Errorf("trace: line %d: want start count, got %q", r.line, text)
+		}
+		start, err1 := strconv.ParseUint(fields[0], 10, 64)
+		count, err2 := strconv.ParseUint(fields[1], 10, 64)
+		if err1 != nil || err2 != nil {
+			return Record{}, fmt.Errorf("trace: line %d: invalid block range %q", r.line, text)
+		}
+		r.next, r.left = start, count
+	}
+	rec := Record{Op: workload.OpGet, Key: strconv.FormatUint(r.next, 10)}
+	r.next++
+	r.left--
+	return rec, nil
+}
@@ -0,0 +1,145 @@
+package trace
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"benchmark-gocache/adapter"
+	"benchmark-gocache/workload"
+)
+
+func read(t *testing.T, f Format, in string) []Record {
+	t.Helper()
+	r, err := NewReader(f, strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	recs, err := ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll(%s) error: %v", f, err)
+	}
+	return recs
+}
+
+func TestReader_CSV(t *testing.T) {
+	recs := read(t, CSV, `op,key,value_size,ttl,timestamp
+# comment
+set,user:1,128,10m,1000
+get,user:1,,,1005
+
+del, user:1
+get,user:2,0,30,1010
+`)
+	want := []Record{
+		{Op: workload.OpSet, Key: "user:1", ValueSize: 128, TTL: 10 * time.Minute, HasTTL: true, Timestamp: 1000},
+		{Op: workload.OpGet, Key: "user:1", Timestamp: 1005},
+		{Op: workload.OpDelete, Key: "user:1"},
+		{Op: workload.OpGet, Key: "user:2", TTL: 30 * time.Second, HasTTL: true, Timestamp: 1010},
+	}
+	if len(recs) != len(want) {
+		t.Fatalf("got %d records, want %d: %+v", len(recs), len(want), recs)
+	}
+	for i := range want {
+		if recs[i] != want[i] {
+			t.Errorf("record %d = %+v, want %+v", i, recs[i], want[i])
+		}
+	}
+}
+
+func TestReader_CSVErrors(t *testing.T) {
+	for _, in := range []string{
+		"put,key",
+		"get",
+		"set,key,-1",
+		"set,key,10,forever",
+		"get,key,,,yesterday",
+	} {
+		r, _ := NewReader(CSV, strings.NewReader(in))
+		if _, err := ReadAll(r); err == nil {
+			t.Errorf("ReadAll(%q) expected an error", in)
+		}
+	}
+}
+
+func TestReader_LIRS(t *testing.T) {
+	recs := read(t, LIRS, "12\n7\n\n12\n")
+	keys := []string{"12", "7", "12"}
+	if len(recs) != len(keys) {
+		t.Fatalf("got %d records, want %d", len(recs), len(keys))
+	}
+	for i, k := range keys {
+		if recs[i].Op != workload.OpGet || recs[i].Key != k {
+			t.Errorf("record %d = %+v, want get %s", i, recs[i], k)
+		}
+	}
+}
+
+func TestReader_ARC(t *testing.T) {
+	recs := read(t, ARC, "100 3 0 0\n7 1 0 1\n")
+	keys := []string{"100", "101", "102", "7"}
+	if len(recs) != len(keys) {
+		t.Fatalf("got %d records, want %d", len(recs), len(keys))
+	}
+	for i, k := range keys {
+		if recs[i].Key != k {
+			t.Errorf("record %d key = %q, want %q", i, recs[i].Key, k)
+		}
+	}
+}
+
+func TestReplay(t *testing.T) {
+	c, err := adapter.New("v1", adapter.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	recs := read(t, LIRS, "a\nb\na\nc\na\nb\n")
+	res := Replay(c, recs, Options{FillOnMiss: true})
+
+	if got := res.Total(); got != 6 {
+		t.Errorf("Total() = %d, want 6", got)
+	}
+	if res.Hits != 3 || res.Fills != 3 {
+		t.Errorf("hits, fills = %d, %d, want 3, 3", res.Hits, res.Fills)
+	}
+	if got := res.HitRatio(); got != 0.5 {
+		t.Errorf("HitRatio() = %v, want 0.5", got)
+	}
+	if l := res.Latency[workload.OpGet]; l.Max == 0 || l.P50 > l.Max {
+		t.Errorf("unexpected get latency %+v", l)
+	}
+}
+
+func TestReplay_NoFill(t *testing.T) {
+	c, _ := adapter.New("v9", adapter.Config{})
+	defer c.Close()
+
+	recs := read(t, CSV, "get,a\nset,a\nget,a\ndelete,a\nget,a\n")
+	res := Replay(c, recs, Options{})
+	if res.Hits != 1 || res.Fills != 0 || res.Ops[workload.OpGet] != 3 {
+		t.Errorf("unexpected result %+v", res)
+	}
+}
+
+func TestReplay_TTL(t *testing.T) {
+	c, _ := adapter.New("v9", adapter.Config{})
+	defer c.Close()
+
+	// An explicit ttl of 0 never expires; a missing one takes Options.TTL.
+	recs := read(t, CSV, "set,explicit,,0\nset,default\nset,short,,0.01\n")
+	if !recs[0].HasTTL || recs[1].HasTTL {
+		t.Fatalf("HasTTL = %v, %v; want true, false", recs[0].HasTTL, recs[1].HasTTL)
+	}
+	Replay(c, recs, Options{TTL: 10 * time.Millisecond})
+	time.Sleep(30 * time.Millisecond)
+	if _, ok := c.Get("explicit"); !ok {
+		t.Error("the key set with a ttl of 0 expired")
+	}
+	for _, key := range []string{"default", "short"} {
+		if _, ok := c.Get(key); ok {
+			t.Errorf("%q did not expire", key)
+		}
+	}
+}
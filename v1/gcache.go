@@ -1,8 +1,12 @@
 package v1
 
 import (
+	"container/list"
+	"context"
 	"sync"
 	"time"
+
+	"benchmark-gocache/xfetch"
 )
 
 const (
@@ -13,12 +17,21 @@ const (
 type Item struct {
 	value   interface{}
 	expires int64
+	delta   int64         // time the value took to compute, for SetXFetch
+	elem    *list.Element // position in cache.lru while the cache is bounded
 }
 
 type cache struct {
-	mu    sync.RWMutex
-	ttl   time.Duration
-	items map[string]*Item
+	mu         sync.RWMutex
+	ttl        time.Duration
+	items      map[string]*Item
+	maxEntries int
+	lru        *list.List // keys from most to least recently used; nil while unbounded
+	evictions  uint64
+	early      xfetch.Settings
+
+	cancel context.CancelFunc
+	exited chan struct{}
 }
 
 type Cache struct {
@@ -26,6 +39,11 @@ type Cache struct {
 }
 
 func New(ttl time.Duration) *Cache {
+	return NewWithContext(context.Background(), ttl)
+}
+
+// NewWithContext is like New, but the cleanup goroutine also stops when ctx is done.
+func NewWithContext(ctx context.Context, ttl time.Duration) *Cache {
 	c := &Cache{
 		cache: &cache{
 			ttl:   ttl,
@@ -34,25 +52,113 @@ func New(ttl time.Duration) *Cache {
 	}
 
 	if ttl > 0 {
-		go c.cleanExpired()
+		ctx, c.cancel = context.WithCancel(ctx)
+		c.exited = make(chan struct{})
+		go c.cleanExpired(ctx)
 	}
 
 	return c
 }
 
+// Close stops the cleanup goroutine and waits for it to return. It is safe to
+// call more than once; the cache stays usable, but expired items are then
+// only removed when read.
+func (c *Cache) Close() error {
+	if c.cancel != nil {
+		c.cancel()
+		<-c.exited
+	}
+	return nil
+}
+
 func (c *Cache) Set(key string, value interface{}, ttl time.Duration) {
+	c.set(key, value, ttl, 0)
+}
+
+func (c *Cache) set(key string, value interface{}, ttl, delta time.Duration) {
 	var expires int64
 	if ttl == DefaultExpiration {
 		ttl = c.ttl
 	}
 	if ttl > 0 {
-		expires = time.Now().Add(ttl).UnixNano()
+		expires = time.Now().Add(c.early.TTL(ttl)).UnixNano()
 	}
 
-	c.mu.Lock()
-	c.items[key] = &Item{
+	item := &Item{
 		value:   value,
 		expires: expires,
+		delta:   int64(delta),
+	}
+	c.mu.Lock()
+	if c.lru != nil {
+		if old, exists := c.items[key]; exists {
+			item.elem = old.elem
+			c.lru.MoveToFront(item.elem)
+		} else {
+			for len(c.items) >= c.maxEntries {
+				c.evict()
+			}
+			item.elem = c.lru.PushFront(key)
+		}
+	}
+	c.items[key] = item
+	c.mu.Unlock()
+}
+
+// SetMaxEntries bounds the cache to n items; n <= 0 removes the bound.
+// When full, Set evicts the least recently used item to make room for a new
+// key. Items already stored when the bound is set start in arbitrary order.
+func (c *Cache) SetMaxEntries(n int) {
+	c.mu.Lock()
+	c.maxEntries = n
+	switch {
+	case n <= 0:
+		c.lru = nil
+	case c.lru == nil:
+		c.lru = list.New()
+		for key, item := range c.items {
+			item.elem = c.lru.PushFront(key)
+		}
+	}
+	for n > 0 && len(c.items) > n {
+		c.evict()
+	}
+	c.mu.Unlock()
+}
+
+// Evictions returns how many items have been evicted to respect the bound
+// set by SetMaxEntries. Expired and deleted items are not counted.
+func (c *Cache) Evictions() uint64 {
+	c.mu.RLock()
+	n := c.evictions
+	c.mu.RUnlock()
+	return n
+}
+
+// evict removes the least recently used item. The caller must hold c.mu
+// and the cache must be bounded.
+func (c *Cache) evict() {
+	elem := c.lru.Back()
+	c.lru.Remove(elem)
+	delete(c.items, elem.Value.(string))
+	c.evictions++
+}
+
+// remove deletes key, whose item is item, from the cache.
+// The caller must hold c.mu.
+func (c *Cache) remove(key string, item *Item) {
+	if c.lru != nil {
+		c.lru.Remove(item.elem)
+	}
+	delete(c.items, key)
+}
+
+// touch marks item, stored under key, as the most recently used.
+func (c *Cache) touch(key string, item *Item) {
+	c.mu.Lock()
+	// The item may have been replaced or removed since Get found it.
+	if c.lru != nil && c.items[key] == item {
+		c.lru.MoveToFront(item.elem)
 	}
 	c.mu.Unlock()
 }
@@ -60,6 +166,7 @@ func (c *Cache) Set(key string, value interface{}, ttl time.Duration) {
 func (c *Cache) Get(key string) (interface{}, bool) {
 	c.mu.RLock()
 	item, exists := c.items[key]
+	bounded := c.lru != nil
 	c.mu.RUnlock()
 
 	if !exists {
@@ -67,26 +174,43 @@ func (c *Cache) Get(key string) (interface{}, bool) {
 	}
 
 	// Se expirado, remove e retorna false
-	if item.expires > 0 && time.Now().UnixNano() > item.expires {
-		c.Delete(key)
-		return nil, false
+	if item.expires > 0 {
+		now := time.Now().UnixNano()
+		if now > item.expires {
+			c.Delete(key)
+			return nil, false
+		}
+		if c.early.Early(now, item.expires, item.delta) {
+			return nil, false
+		}
 	}
 
+	if bounded {
+		c.touch(key, item)
+	}
 	return item.value, true
 }
 
 func (c *Cache) Delete(key string) {
 	c.mu.Lock()
-	delete(c.items, key)
+	if item, exists := c.items[key]; exists {
+		c.remove(key, item)
+	}
 	c.mu.Unlock()
 }
 
-func (c *Cache) cleanExpired() {
+func (c *Cache) cleanExpired(ctx context.Context) {
+	defer close(c.exited)
 	ticker := time.NewTicker(c.ttl)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		c.clean()
+	for {
+		select {
+		case <-ticker.C:
+			c.clean()
+		case <-ctx.Done():
+			return
+		}
 	}
 }
 
@@ -95,8 +219,15 @@ func (c *Cache) clean() {
 	c.mu.Lock()
 	for key, item := range c.items {
 		if item.expires > 0 && now > item.expires {
-			delete(c.items, key)
+			c.remove(key, item)
 		}
 	}
 	c.mu.Unlock()
 }
+
+func (c *Cache) Len() int {
+	c.mu.RLock()
+	n := len(c.items)
+	c.mu.RUnlock()
+	return n
+}
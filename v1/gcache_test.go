@@ -1,9 +1,14 @@
 package v1
 
 import (
+	"context"
+	"io"
 	"reflect"
+	"strconv"
 	"testing"
 	"time"
+
+	"benchmark-gocache/conformance"
 )
 
 func TestNew(t *testing.T) {
@@ -19,7 +24,12 @@ func TestNew(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := New(tt.args.ttl); !reflect.DeepEqual(got, tt.want) {
+			got := New(tt.args.ttl)
+			defer got.Close()
+			defer tt.want.Close()
+			// Each cache owns its cleanup goroutine, so only compare the
+			// contents.
+			if got.ttl != tt.want.ttl || !reflect.DeepEqual(got.items, tt.want.items) {
 				t.Errorf("New() = %v, want %v", got, tt.want)
 			}
 		})
@@ -221,3 +231,95 @@ func TestCache_clean(t *testing.T) {
 		})
 	}
 }
+
+func TestCache_MaxEntries(t *testing.T) {
+	cache := New(10 * time.Minute)
+	cache.SetMaxEntries(100)
+
+	for i := 0; i < 1000; i++ {
+		cache.Set(strconv.Itoa(i), i, DefaultExpiration)
+	}
+	if n := cache.Len(); n != 100 {
+		t.Errorf("Len() = %d, want 100", n)
+	}
+	if _, found := cache.Get("999"); !found {
+		t.Errorf("Expected the last key set to be present")
+	}
+
+	cache.SetMaxEntries(10)
+	if n := cache.Len(); n != 10 {
+		t.Errorf("Len() after shrinking = %d, want 10", n)
+	}
+
+	cache.SetMaxEntries(0)
+	for i := 0; i < 1000; i++ {
+		cache.Set(strconv.Itoa(i), i, DefaultExpiration)
+	}
+	if n := cache.Len(); n != 1000 {
+		t.Errorf("Len() after removing the bound = %d, want 1000", n)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	conformance.Run(t, func(ttl time.Duration) conformance.Cache {
+		return New(ttl)
+	})
+}
+
+func TestEarlyExpiration(t *testing.T) {
+	conformance.RunEarly(t, func(ttl time.Duration) conformance.EarlyCache {
+		return New(ttl)
+	})
+}
+
+func TestLifecycle(t *testing.T) {
+	conformance.RunLifecycle(t, func(ctx context.Context, ttl time.Duration) io.Closer {
+		return NewWithContext(ctx, ttl)
+	})
+}
+
+func TestCache_LRU(t *testing.T) {
+	cache := New(10 * time.Minute)
+	cache.SetMaxEntries(3)
+
+	cache.Set("a", 1, DefaultExpiration)
+	cache.Set("b", 2, DefaultExpiration)
+	cache.Set("c", 3, DefaultExpiration)
+	cache.Get("a")                        // b is now the least recently used
+	cache.Set("c", 30, DefaultExpiration) // Overwriting does not evict
+	cache.Set("d", 4, DefaultExpiration)
+
+	if _, found := cache.Get("b"); found {
+		t.Errorf("Expected b to be evicted")
+	}
+	for _, key := range []string{"a", "c", "d"} {
+		if _, found := cache.Get(key); !found {
+			t.Errorf("Expected %s to be present", key)
+		}
+	}
+	if n := cache.Evictions(); n != 1 {
+		t.Errorf("Evictions() = %d, want 1", n)
+	}
+
+	// Deleted and expired items free their slot without an eviction.
+	cache.Delete("a")
+	cache.Set("e", 5, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if _, found := cache.Get("e"); found {
+		t.Errorf("Expected e to be expired")
+	}
+	cache.Set("f", 6, DefaultExpiration)
+	if n, ev := cache.Len(), cache.Evictions(); n != 3 || ev != 1 {
+		t.Errorf("Len() = %d, Evictions() = %d; want 3 and 1", n, ev)
+	}
+
+	// Shrinking evicts from the least recently used end.
+	cache.Get("c")
+	cache.SetMaxEntries(1)
+	if _, found := cache.Get("c"); !found {
+		t.Errorf("Expected c, the most recently used, to survive shrinking")
+	}
+	if n := cache.Evictions(); n != 3 {
+		t.Errorf("Evictions() after shrinking = %d, want 3", n)
+	}
+}
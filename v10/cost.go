@@ -0,0 +1,121 @@
+package v10
+
+import (
+	"reflect"
+	"time"
+)
+
+// Sizer returns the cost of a value, usually its size in bytes.
+type Sizer func(value interface{}) int64
+
+// DefaultSizer returns the length of strings and byte slices, and the
+// shallow size of the type of other values.
+func DefaultSizer(value interface{}) int64 {
+	switch v := value.(type) {
+	case nil:
+		return 0
+	case string:
+		return int64(len(v))
+	case []byte:
+		return int64(len(v))
+	}
+	return int64(reflect.TypeOf(value).Size())
+}
+
+// SetSizer sets how values are measured; an item costs its key length plus
+// sizer(value). Stored items are measured again, and evicted as needed to
+// stay within SetMaxCost. A nil sizer stops the accounting unless SetMaxCost
+// is in use.
+func (c *Cache) SetSizer(sizer Sizer) {
+	for _, sh := range c.shards {
+		sh.mu.Lock()
+		if sizer == nil && sh.maxCost > 0 {
+			sh.resize(DefaultSizer)
+		} else {
+			sh.resize(sizer)
+		}
+		for sh.maxCost > 0 && sh.cost > sh.maxCost {
+			sh.evict()
+		}
+		sh.unlock()
+	}
+}
+
+// SetMaxCost bounds the total cost to about n, split evenly across shards;
+// n <= 0 removes the bound. A full shard evicts arbitrary items, and never
+// stores an item costing more than its share. Costs default to DefaultSizer.
+func (c *Cache) SetMaxCost(n int64) {
+	var per int64
+	if n > 0 {
+		per = (n + numShards - 1) / numShards
+	}
+	for _, sh := range c.shards {
+		sh.mu.Lock()
+		sh.maxCost = per
+		if per > 0 && sh.sizer == nil {
+			sh.resize(DefaultSizer)
+		}
+		for per > 0 && sh.cost > per {
+			sh.evict()
+		}
+		sh.unlock()
+	}
+}
+
+// SetWithCost is like Set with an explicit cost. It reports false, and removes
+// key, if the item costs more than a shard's share of the bound.
+func (c *Cache) SetWithCost(key string, value interface{}, cost int64, ttl time.Duration) bool {
+	return c.set(key, value, max(cost, 0), ttl, 0)
+}
+
+// Cost returns the total cost of the items stored.
+func (c *Cache) Cost() int64 {
+	var n int64
+	for _, sh := range c.shards {
+		sh.mu.RLock()
+		n += sh.cost
+		sh.mu.RUnlock()
+	}
+	return n
+}
+
+// measure sets the cost of item to cost, or measures it if cost < 0.
+func (sh *shard) measure(item *Item, cost int64) {
+	switch {
+	case cost >= 0:
+		item.cost = cost
+	case sh.sizer != nil:
+		item.cost = int64(len(item.key)) + sh.sizer(item.value)
+	}
+}
+
+// fit evicts items until item fits within the cost bound, counting the item it
+// replaces as freed. It reports false if item alone exceeds the bound.
+func (sh *shard) fit(h uint32, item *Item) bool {
+	if item.cost > sh.maxCost {
+		return false
+	}
+	for {
+		var old int64
+		if cur := sh.lookup(h, item.key); cur != nil {
+			old = cur.cost
+		}
+		if sh.cost-old+item.cost <= sh.maxCost {
+			return true
+		}
+		sh.evict()
+	}
+}
+
+// resize makes sizer the shard's Sizer and measures every item again.
+func (sh *shard) resize(sizer Sizer) {
+	sh.sizer = sizer
+	sh.cost = 0
+	for _, head := range sh.items {
+		for item := head; item != nil; item = item.next {
+			item.cost = 0
+			sh.measure(item, -1)
+			sh.cost += item.cost
+		}
+	}
+}
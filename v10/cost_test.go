@@ -0,0 +1,114 @@
+package v10
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"benchmark-gocache/policy"
+)
+
+type point struct{ x, y int32 }
+
+func TestDefaultSizer(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  int64
+	}{
+		{nil, 0},
+		{"hello", 5},
+		{[]byte("hi"), 2},
+		{int64(1), 8},
+		{point{1, 2}, 8},
+	}
+	for _, tt := range tests {
+		if got := DefaultSizer(tt.value); got != tt.want {
+			t.Errorf("DefaultSizer(%#v) = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestCache_MaxCost(t *testing.T) {
+	const maxCost = 64 << 10
+	cache := New(time.Minute)
+	defer cache.Close()
+	cache.SetMaxCost(maxCost)
+
+	val := make([]byte, 1000)
+	for i := 0; i < 1000; i++ {
+		cache.Set(strconv.Itoa(i), val, DefaultExpiration)
+		if c := cache.Cost(); c > maxCost {
+			t.Fatalf("Cost() = %d after %d Sets, want at most %d", c, i+1, maxCost)
+		}
+	}
+	if n := cache.Len(); n == 0 || int64(n)*1000 > maxCost {
+		t.Errorf("Len() = %d, want between 1 and %d", n, maxCost/1000)
+	}
+
+	// Overwriting a key replaces its cost.
+	cache.SetMaxCost(0)
+	before := cache.Cost()
+	cache.Set("999", "small", DefaultExpiration)
+	if got, want := cache.Cost(), before-int64(len(val))+5; got != want {
+		t.Errorf("Cost() after overwrite = %d, want %d", got, want)
+	}
+
+	// An item larger than a shard's share is refused and its key removed.
+	cache.SetMaxCost(maxCost)
+	if cache.SetWithCost("999", "huge", maxCost, DefaultExpiration) {
+		t.Errorf("SetWithCost accepted an item costing the whole budget")
+	}
+	if _, found := cache.Get("999"); found {
+		t.Errorf("Expected the refused key to be removed")
+	}
+}
+
+func TestCache_SetSizer(t *testing.T) {
+	cache := New(time.Minute)
+	defer cache.Close()
+	cache.Set("a", point{1, 2}, DefaultExpiration)
+	if c := cache.Cost(); c != 0 {
+		t.Errorf("Cost() without a Sizer = %d, want 0", c)
+	}
+
+	// Existing items are measured again.
+	cache.SetSizer(func(interface{}) int64 { return 100 })
+	cache.Set("b", point{3, 4}, DefaultExpiration)
+	if c := cache.Cost(); c != 2*(1+100) {
+		t.Errorf("Cost() = %d, want %d", c, 2*(1+100))
+	}
+
+	cache.Delete("a")
+	if !cache.SetWithCost("c", "value", 7, DefaultExpiration) {
+		t.Fatalf("SetWithCost refused an item in an unbounded cache")
+	}
+	if c := cache.Cost(); c != 101+7 {
+		t.Errorf("Cost() after Delete and SetWithCost = %d, want %d", c, 101+7)
+	}
+}
+
+func TestCache_SetSizerEvicts(t *testing.T) {
+	const maxCost = 100 * numShards
+	cache := New(time.Minute)
+	defer cache.Close()
+	cache.SetMaxCost(maxCost)
+	for i := 0; i < 1000; i++ {
+		cache.SetWithCost(strconv.Itoa(i), i, 1, DefaultExpiration)
+	}
+	n := cache.Len()
+	evicted := 0
+	cache.OnEvict(func(key string, value any, reason policy.EvictReason) {
+		if reason == policy.Capacity {
+			evicted++
+		}
+	})
+
+	// A larger Sizer evicts items until the cache fits its bound again.
+	cache.SetSizer(func(interface{}) int64 { return 10 })
+	if c := cache.Cost(); c > maxCost {
+		t.Errorf("Cost() after SetSizer = %d, want at most %d", c, maxCost)
+	}
+	if got := cache.Len(); evicted == 0 || got+evicted != n {
+		t.Errorf("Len() = %d with %d evictions reported, want %d in all", got, evicted, n)
+	}
+}
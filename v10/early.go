@@ -0,0 +1,25 @@
+package v10
+
+import "time"
+
+// SetWithDelta is Set for a value that took delta to compute. With SetXFetch,
+// Gets use delta to expire the item early.
+func (c *Cache) SetWithDelta(key string, value interface{}, ttl, delta time.Duration) {
+	c.set(key, value, -1, ttl, delta)
+}
+
+// SetXFetch makes Get report a miss for an item that is about to expire, with
+// a probability that grows as its expiry nears and with its delta, so that a
+// single caller recomputes it while the others keep hitting; see package
+// xfetch. Items stored by Set have no delta and never expire early.
+// beta <= 0 turns it off; xfetch.DefaultBeta is a sensible value.
+func (c *Cache) SetXFetch(beta float64) {
+	c.early.SetBeta(beta)
+}
+
+// SetTTLJitter shortens the ttl of each item stored afterwards by a random
+// fraction, up to fraction, of its length, so that items stored together
+// expire apart. 0 turns it off.
+func (c *Cache) SetTTLJitter(fraction float64) {
+	c.early.SetJitter(fraction)
+}
@@ -0,0 +1,57 @@
+package v11
+
+import "benchmark-gocache/policy"
+
+// SetEvictionPolicy bounds the cache to about n items, split evenly across
+// shards, and evicts the items chosen by a policy built by newPolicy for each
+// shard, such as policy.NewSIEVE. It also picks the items evicted to honour
+// SetMaxCost. n <= 0 or a nil newPolicy removes the bound, and items already
+// stored are handed to the policy in arbitrary order.
+func (c *Cache) SetEvictionPolicy(n int, newPolicy policy.Factory) {
+	per := 0
+	if n > 0 && newPolicy != nil {
+		per = (n + numShards - 1) / numShards
+	}
+	for _, sh := range c.shards {
+		sh.mu.Lock()
+		sh.maxEntries = per
+		sh.setPolicy(nil)
+		if per > 0 {
+			p := newPolicy(per)
+			for _, head := range sh.items {
+				for item := head; item != nil; item = item.next {
+					p.OnInsert(item.key)
+				}
+			}
+			sh.setPolicy(p)
+		}
+		for per > 0 && sh.count > per {
+			sh.evict()
+		}
+		sh.unlock()
+	}
+}
+
+// setPolicy replaces the shard's policy. The caller must hold sh.mu for writing.
+func (sh *shard) setPolicy(p policy.EvictionPolicy) {
+	sh.policyMu.Lock()
+	sh.policy = p
+	sh.policyMu.Unlock()
+}
+
+// notify reports event on key to the shard's policy, if it still has one.
+func (sh *shard) notify(event func(policy.EvictionPolicy, string), key string) {
+	sh.policyMu.Lock()
+	if sh.policy != nil {
+		event(sh.policy, key)
+	}
+	sh.policyMu.Unlock()
+}
+
+// victim asks the shard's policy for the key to evict.
+// The caller must hold sh.mu for writing.
+func (sh *shard) victim() (string, bool) {
+	sh.policyMu.Lock()
+	defer sh.policyMu.Unlock()
+	return sh.policy.Victim()
+}
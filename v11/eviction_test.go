@@ -0,0 +1,63 @@
+package v11
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"benchmark-gocache/policy"
+)
+
+func TestCache_EvictionPolicy(t *testing.T) {
+	// Policies that keep a key read between every two Sets.
+	keepsHot := map[string]bool{"lru": true, "lfu": true, "s3-fifo": true, "sieve": true}
+
+	for _, name := range policy.Names() {
+		t.Run(name, func(t *testing.T) {
+			newPolicy, _ := policy.Lookup(name)
+			cache := New(10 * time.Minute)
+			defer cache.Close()
+			cache.SetEvictionPolicy(10*numShards, newPolicy)
+
+			cache.Set("hot", 0, DefaultExpiration)
+			for i := 0; i < 1000; i++ {
+				if _, found := cache.Get("hot"); !found && keepsHot[name] {
+					t.Fatalf("hot key evicted after %d Sets", i)
+				}
+				cache.Set(strconv.Itoa(i), i, DefaultExpiration)
+				if i%10 == 0 {
+					cache.Delete(strconv.Itoa(i))
+				}
+			}
+			for i := 0; i < 1000; i++ {
+				cache.Set("more-"+strconv.Itoa(i), i, DefaultExpiration)
+			}
+			if n := cache.Len(); n != 10*numShards {
+				t.Errorf("Len() = %d, want %d", n, 10*numShards)
+			}
+		})
+	}
+}
+
+func TestCache_EvictionPolicyCollision(t *testing.T) {
+	cache := New(10 * time.Minute)
+	defer cache.Close()
+	cache.SetEvictionPolicy(numShards, policy.NewLRU)
+
+	cache.Set("a", 1, DefaultExpiration)
+	cache.Set("\x00a", 2, DefaultExpiration)
+	if _, found := cache.Get("a"); found {
+		t.Errorf("Expected a to be evicted by \\x00a, which shares its hash")
+	}
+	if v, found := cache.Get("\x00a"); !found || v != 2 {
+		t.Errorf("Get(\\x00a) = %v, %v; want 2, true", v, found)
+	}
+
+	cache.SetMaxEntries(0)
+	for i := 0; i < 100; i++ {
+		cache.Set(strconv.Itoa(i), i, DefaultExpiration)
+	}
+	if n := cache.Len(); n != 101 {
+		t.Errorf("Len() after removing the bound = %d, want 101", n)
+	}
+}
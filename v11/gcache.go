@@ -1,59 +1,114 @@
 package v11
 
 import (
+	"context"
 	"github.com/cespare/xxhash/v2"
 	"sync"
 	"time"
+
+	"benchmark-gocache/loader"
+	"benchmark-gocache/policy"
+	"benchmark-gocache/xfetch"
 )
 
 const (
-	DefaultExpiration time.Duration = 0    // Uses default TTL if not specified
-	NoExpiration      time.Duration = -1   // Items with no expiration time
-	numShards                       = 8    // Number of shards for concurrent access
-	ringSize                        = 4096 // Size of the expiration ring buffer
+	DefaultExpiration time.Duration = 0               // Uses default TTL if not specified
+	NoExpiration      time.Duration = -1              // Items with no expiration time
+	numShards                       = 8               // Number of shards for concurrent access
+	sweepBatch                      = 1024            // Expired items removed per shard lock acquisition
+	sweepLimit                      = 16 * sweepBatch // Expired items removed per shard and cleanup tick
+	shrinkMin                       = 1024            // Peak item count from which emptied shard maps are rebuilt
 )
 
-// ringNode represents an entry in the expiration ring buffer.
-type ringNode struct {
-	key     uint64 // Hashed key
-	expires int64  // Expiration timestamp in nanoseconds
-}
-
 // shard is a partition of the cache with its own locking mechanism.
 type shard struct {
-	mu       sync.RWMutex     // Mutex for concurrent access
-	items    map[uint64]*Item // Cached items
-	ringBuf  []ringNode       // Ring buffer for tracking expiration
-	ringHead int              // Current position in the ring buffer
+	mu      sync.RWMutex     // Mutex for concurrent access
+	items   map[uint64]*Item // Cached items, chained by hash
+	buckets map[int64]*Item  // Items with a TTL, listed by the tick in which they expire
+	tick    int64            // Width of an expiry bucket in nanoseconds; 0 disables the index
+	swept   int64            // Buckets before this tick have been emptied by cleanup
+	count   int              // Number of items, including chained ones
+	peak    int              // Largest count since the maps were last rebuilt
+
+	maxEntries int   // Per-shard item limit; 0 means unbounded
+	cost       int64 // Total cost of the items
+	maxCost    int64 // Per-shard cost limit; 0 means unbounded
+	sizer      Sizer // Measures the cost of values; nil disables the accounting
+
+	policyMu sync.Mutex              // Guards policy, so Get can report hits under sh.mu.RLock
+	policy   policy.EvictionPolicy   // Policy set by SetEvictionPolicy; nil if there is none
+	hash     func(key string) uint64 // Hashes the keys chosen by policy
+
+	evictions policy.Evictions // Items removed under sh.mu, for the OnEvict callback
 }
 
 // Item represents a single cache entry.
 type Item struct {
-	value   any   // Stored value
-	expires int64 // Expiration timestamp
+	key     string // Original key, compared on lookup to resolve hash collisions
+	hash    uint64 // Hash of key
+	value   any    // Stored value
+	expires int64  // Expiration timestamp
+	delta   int64  // Time the value took to compute, for SetXFetch
+	next    *Item  // Next item whose key has the same hash
+	cost    int64  // Cost of the item, if the shard accounts for costs
+
+	expPrev, expNext *Item // Neighbours in the expiry bucket
 }
 
 // Cache is a sharded in-memory cache with expiration handling.
 type Cache struct {
-	shards [numShards]*shard // Array of shards to reduce contention
-	ttl    time.Duration     // Default time-to-live for cache entries
+	shards [numShards]*shard  // Array of shards to reduce contention
+	ttl    time.Duration      // Default time-to-live for cache entries
+	cancel context.CancelFunc // Stops the cleanup goroutine
+	exited chan struct{}      // Closed when the cleanup goroutine returns
+	loads  loader.Group       // Loads in flight for GetOrLoad
+	early  xfetch.Settings    // Early expiration and ttl jitter settings
 }
 
 // New creates a new instance of Cache with a given TTL.
 func New(ttl time.Duration) *Cache {
+	return NewWithContext(context.Background(), ttl)
+}
+
+// NewWithContext is like New, but the cleanup goroutine also stops when ctx is done.
+func NewWithContext(ctx context.Context, ttl time.Duration) *Cache {
 	c := &Cache{ttl: ttl}
+	// Expiry buckets are as wide as the cleanup interval, so each run
+	// usually empties a single bucket per shard.
+	var tick, now int64
+	if ttl > 0 {
+		tick = int64(ttl / 2)
+		now = time.Now().UnixNano()
+	}
 	for i := 0; i < numShards; i++ {
 		c.shards[i] = &shard{
 			items:   make(map[uint64]*Item),
-			ringBuf: make([]ringNode, ringSize),
+			buckets: make(map[int64]*Item),
+			tick:    tick,
+			hash:    c.hashKey,
+		}
+		if tick > 0 {
+			c.shards[i].swept = now / tick
 		}
 	}
 	if ttl > 0 {
-		go c.cleanup()
+		ctx, c.cancel = context.WithCancel(ctx)
+		c.exited = make(chan struct{})
+		go c.cleanup(ctx)
 	}
 	return c
 }
 
+// Close stops the cleanup goroutine and waits for it to return. It is safe to
+// call more than once; afterwards expired items are only removed when read.
+func (c *Cache) Close() error {
+	if c.cancel != nil {
+		c.cancel()
+		<-c.exited
+	}
+	return nil
+}
+
 // hashKey computes a hash value for a given string key.
 //
 // The function selects the hashing algorithm dynamically based on the key length:
@@ -89,22 +144,41 @@ func (c *Cache) getShard(k uint64) *shard {
 
 // Set inserts a value into the cache with an optional TTL.
 func (c *Cache) Set(key string, value any, ttl time.Duration) {
+	c.set(key, value, -1, ttl, 0)
+}
+
+// set stores value under key with the given cost, or the measured cost if
+// cost < 0, and reports false if the item exceeds the cost bound.
+func (c *Cache) set(key string, value any, cost int64, ttl, delta time.Duration) bool {
 	var exp int64
 	if ttl == DefaultExpiration {
 		ttl = c.ttl
 	}
 	if ttl > 0 {
-		exp = time.Now().Add(ttl).UnixNano()
+		exp = time.Now().Add(c.early.TTL(ttl)).UnixNano()
 	}
 
 	hashed := c.hashKey(key)
 	sh := c.getShard(hashed)
 
+	item := &Item{key: key, hash: hashed, value: value, expires: exp, delta: int64(delta)}
 	sh.mu.Lock()
-	sh.items[hashed] = &Item{value: value, expires: exp}
-	sh.ringBuf[sh.ringHead] = ringNode{key: hashed, expires: exp}
-	sh.ringHead = (sh.ringHead + 1) % ringSize
-	sh.mu.Unlock()
+	sh.measure(item, cost)
+	if sh.maxCost > 0 && !sh.fit(hashed, item) {
+		sh.remove(hashed, key, policy.Capacity)
+		sh.unlock()
+		return false
+	}
+	if sh.maxEntries > 0 {
+		if sh.lookup(hashed, key) == nil {
+			for sh.count >= sh.maxEntries {
+				sh.evict()
+			}
+		}
+	}
+	sh.store(hashed, item)
+	sh.unlock()
+	return true
 }
 
 // Get retrieves a value from the cache.
@@ -114,18 +188,28 @@ func (c *Cache) Get(key string) (any, bool) {
 	sh := c.getShard(hashed)
 
 	sh.mu.RLock()
-	item, exists := sh.items[hashed]
+	item := sh.lookup(hashed, key)
+	tracked := sh.policy != nil
 	sh.mu.RUnlock()
 
-	if !exists {
+	if item == nil {
 		return nil, false
 	}
 
-	if item.expires > 0 && time.Now().UnixNano() > item.expires {
-		c.Delete(key) // Remove expired item
-		return nil, false
+	if item.expires > 0 {
+		now := time.Now().UnixNano()
+		if now > item.expires {
+			c.removeExpired(hashed, item)
+			return nil, false
+		}
+		if c.early.Early(now, item.expires, item.delta) {
+			return nil, false
+		}
 	}
 
+	if tracked {
+		sh.notify(policy.EvictionPolicy.OnAccess, key)
+	}
 	return item.value, true
 }
 
@@ -135,27 +219,262 @@ func (c *Cache) Delete(key string) {
 	sh := c.getShard(hashed)
 
 	sh.mu.Lock()
-	delete(sh.items, hashed)
-	sh.mu.Unlock()
+	sh.remove(hashed, key, policy.Deleted)
+	sh.unlock()
+}
+
+// removeExpired removes item, which Get found expired, unless a Set replaced it.
+func (c *Cache) removeExpired(h uint64, item *Item) {
+	sh := c.getShard(h)
+	sh.mu.Lock()
+	if sh.lookup(h, item.key) == item {
+		sh.remove(h, item.key, policy.Expired)
+	}
+	sh.unlock()
+}
+
+// SetMaxEntries bounds the cache to about n items, split evenly across shards.
+// A full shard evicts an arbitrary item for a new key; n <= 0 removes the bound.
+// It replaces any bound set by SetEvictionPolicy.
+func (c *Cache) SetMaxEntries(n int) {
+	per := 0
+	if n > 0 {
+		per = (n + numShards - 1) / numShards
+	}
+	for _, sh := range c.shards {
+		sh.mu.Lock()
+		sh.maxEntries = per
+		sh.setPolicy(nil)
+		for per > 0 && sh.count > per {
+			sh.evict()
+		}
+		sh.unlock()
+	}
+}
+
+// evict removes one item from the shard: the victim of its eviction policy,
+// if it has one, otherwise an arbitrary item. The caller must hold sh.mu.
+func (sh *shard) evict() {
+	if sh.policy != nil {
+		if key, ok := sh.victim(); ok {
+			if h := sh.hash(key); sh.lookup(h, key) != nil {
+				sh.remove(h, key, policy.Capacity)
+				return
+			}
+		}
+	}
+	for h, item := range sh.items {
+		sh.remove(h, item.key, policy.Capacity)
+		return
+	}
+}
+
+// lookup returns the item stored under key, whose hash is h, or nil.
+// The caller must hold sh.mu.
+func (sh *shard) lookup(h uint64, key string) *Item {
+	for item := sh.items[h]; item != nil; item = item.next {
+		if item.key == key {
+			return item
+		}
+	}
+	return nil
+}
+
+// store links item into the chain for h and into the expiry index,
+// replacing the item with the same key if there is one. Items are never
+// modified once linked, except for their links, so Get can read one after
+// releasing the lock.
+// The caller must hold sh.mu for writing.
+func (sh *shard) store(h uint64, item *Item) {
+	sh.index(item)
+	var prev *Item
+	for cur := sh.items[h]; cur != nil; prev, cur = cur, cur.next {
+		if cur.key == item.key {
+			sh.unindex(cur)
+			sh.cost += item.cost - cur.cost
+			item.next = cur.next
+			sh.link(h, prev, item)
+			sh.evictions.Add(cur.key, cur.value, policy.Replaced)
+			if sh.policy != nil {
+				sh.notify(policy.EvictionPolicy.OnAccess, item.key)
+			}
+			return
+		}
+	}
+	item.next = sh.items[h]
+	sh.items[h] = item
+	sh.cost += item.cost
+	sh.count++
+	if sh.count > sh.peak {
+		sh.peak = sh.count
+	}
+	if sh.policy != nil {
+		sh.notify(policy.EvictionPolicy.OnInsert, item.key)
+	}
+}
+
+// remove unlinks the item stored under key, if any, and records it for the
+// OnEvict callback with reason.
+// The caller must hold sh.mu for writing.
+func (sh *shard) remove(h uint64, key string, reason policy.EvictReason) {
+	var prev *Item
+	for cur := sh.items[h]; cur != nil; prev, cur = cur, cur.next {
+		if cur.key == key {
+			sh.unlink(h, prev, cur)
+			sh.evictions.Add(cur.key, cur.value, reason)
+			return
+		}
+	}
+}
+
+// link makes item follow prev in the chain for h, or head it if prev is nil.
+func (sh *shard) link(h uint64, prev, item *Item) {
+	if prev == nil {
+		sh.items[h] = item
+	} else {
+		prev.next = item
+	}
+}
+
+// unlink removes item, which follows prev, from the chain for h and from
+// the expiry index.
+func (sh *shard) unlink(h uint64, prev, item *Item) {
+	if prev == nil && item.next == nil {
+		delete(sh.items, h)
+	} else {
+		sh.link(h, prev, item.next)
+	}
+	sh.unindex(item)
+	if sh.policy != nil {
+		sh.notify(policy.EvictionPolicy.OnDelete, item.key)
+	}
+	sh.cost -= item.cost
+	sh.count--
+}
+
+// indexed reports whether item belongs in the expiry index.
+func (sh *shard) indexed(item *Item) bool {
+	return sh.tick > 0 && item.expires > 0
+}
+
+// bucket returns the tick of the expiry bucket holding item. If the wall
+// clock stepped back, that tick may already have been swept; the item then
+// goes in the next bucket to be swept.
+func (sh *shard) bucket(item *Item) int64 {
+	return max(item.expires/sh.tick, sh.swept)
+}
+
+// index pushes item onto its expiry bucket.
+func (sh *shard) index(item *Item) {
+	if !sh.indexed(item) {
+		return
+	}
+	t := sh.bucket(item)
+	if head := sh.buckets[t]; head != nil {
+		head.expPrev = item
+		item.expNext = head
+	}
+	sh.buckets[t] = item
+}
+
+// unindex removes item from its expiry bucket.
+func (sh *shard) unindex(item *Item) {
+	if !sh.indexed(item) {
+		return
+	}
+	if item.expPrev != nil {
+		item.expPrev.expNext = item.expNext
+	} else if t := sh.bucket(item); item.expNext != nil {
+		sh.buckets[t] = item.expNext
+	} else {
+		delete(sh.buckets, t)
+	}
+	if item.expNext != nil {
+		item.expNext.expPrev = item.expPrev
+	}
+	item.expPrev, item.expNext = nil, nil
+}
+
+// expire removes the items in buckets whose tick has fully elapsed by now,
+// stopping after limit items. It reports whether every such bucket is empty.
+// The caller must hold sh.mu for writing.
+func (sh *shard) expire(now int64, limit int) bool {
+	for end := now / sh.tick; sh.swept < end; sh.swept++ {
+		for item := sh.buckets[sh.swept]; item != nil; item = sh.buckets[sh.swept] {
+			if limit == 0 {
+				return false
+			}
+			sh.remove(item.hash, item.key, policy.Expired)
+			limit--
+		}
+	}
+	return true
+}
+
+// shrink rebuilds the shard's maps once they hold under a quarter of their
+// peak item count. Go maps never release their buckets, so without it the
+// memory of a mass expiry would stay allocated.
+// The caller must hold sh.mu for writing.
+func (sh *shard) shrink() {
+	if sh.peak < shrinkMin || sh.count >= sh.peak/4 {
+		return
+	}
+	items := make(map[uint64]*Item, sh.count)
+	for h, item := range sh.items {
+		items[h] = item
+	}
+	buckets := make(map[int64]*Item, len(sh.buckets))
+	for t, item := range sh.buckets {
+		buckets[t] = item
+	}
+	sh.items, sh.buckets, sh.peak = items, buckets, sh.count
 }
 
-// cleanup periodically removes expired items from the cache.
-func (c *Cache) cleanup() {
+// cleanup periodically empties the expiry buckets whose time has passed,
+// removing every expired item within two intervals of its expiry, unless a
+// shard has more than sweepLimit of them; the rest wait for later ticks.
+func (c *Cache) cleanup(ctx context.Context) {
+	defer close(c.exited)
 	tick := time.NewTicker(c.ttl / 2)
 	defer tick.Stop()
 
-	for range tick.C {
+	for {
+		select {
+		case <-tick.C:
+		case <-ctx.Done():
+			return
+		}
 		now := time.Now().UnixNano()
 		for _, sh := range c.shards {
-			sh.mu.Lock()
-			for i := 0; i < ringSize; i++ {
-				node := &sh.ringBuf[i]
-				if node.expires > 0 && now > node.expires {
-					delete(sh.items, node.key)
-					node.expires = 0
-				}
-			}
-			sh.mu.Unlock()
+			sh.sweep(now)
+		}
+	}
+}
+
+// sweep removes up to sweepLimit items of the shard that expired by now,
+// sweepBatch per lock acquisition, and leaves the rest to the next tick.
+// It rebuilds the shard's maps once no expired bucket is left.
+func (sh *shard) sweep(now int64) {
+	for limit := sweepLimit; limit > 0; limit -= sweepBatch {
+		sh.mu.Lock()
+		done := sh.expire(now, min(limit, sweepBatch))
+		if done {
+			sh.shrink()
+		}
+		sh.unlock()
+		if done {
+			return
 		}
 	}
 }
+
+// Len returns the number of items stored, including expired items not yet removed.
+func (c *Cache) Len() int {
+	n := 0
+	for _, sh := range c.shards {
+		sh.mu.RLock()
+		n += sh.count
+		sh.mu.RUnlock()
+	}
+	return n
+}
@@ -0,0 +1,306 @@
+// Package v12 is a gocache version bounded by ARC, the Adaptive Replacement
+// Cache of Megiddo and Modha ("ARC: A Self-Tuning, Low Overhead Replacement
+// Cache", FAST 2003), with the Set/Get/Delete/TTL API of v1.
+//
+// ARC splits the cache between T1, the keys seen once recently, and T2, the
+// keys seen at least twice. Each has a ghost list, B1 and B2, remembering the
+// keys it evicted without their values. A miss on a key in B1 means T1 was
+// too small, so the target size of T1 grows; a miss on a key in B2 shrinks it.
+// A scan only passes through T1, so it cannot flush the frequently used keys
+// of T2 the way it flushes an LRU cache.
+package v12
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	NoExpiration      time.Duration = -1
+	DefaultExpiration time.Duration = 0
+)
+
+// lists of an entry. t1 and t2 hold items; b1 and b2 only keys.
+const (
+	t1 = iota
+	t2
+	b1
+	b2
+)
+
+// entry is a key in one of the four lists. Ghost entries have no value.
+type entry struct {
+	key     string
+	value   interface{}
+	expires int64
+	list    int           // t1, t2, b1 or b2
+	elem    *list.Element // Position in that list
+}
+
+// Every Get updates the lists, so Cache has a single mutex rather than a
+// read-write one.
+type Cache struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	entries   map[string]*entry
+	lists     [4]*list.List // Indexed by t1, t2, b1 and b2, most recently used first
+	capacity  int           // 0 while unbounded
+	target    int           // ARC's p: the length T1 should have
+	evictions uint64
+
+	cancel context.CancelFunc
+	exited chan struct{}
+}
+
+func New(ttl time.Duration) *Cache {
+	return NewWithContext(context.Background(), ttl)
+}
+
+// NewWithContext is like New, but the cleanup goroutine also stops when ctx is done.
+func NewWithContext(ctx context.Context, ttl time.Duration) *Cache {
+	c := &Cache{ttl: ttl, entries: make(map[string]*entry)}
+	for i := range c.lists {
+		c.lists[i] = list.New()
+	}
+	if ttl > 0 {
+		ctx, c.cancel = context.WithCancel(ctx)
+		c.exited = make(chan struct{})
+		go c.cleanExpired(ctx)
+	}
+	return c
+}
+
+// Close stops the cleanup goroutine and waits for it to return. It is safe to
+// call more than once; the cache stays usable, but expired items are then
+// only removed when read.
+func (c *Cache) Close() error {
+	if c.cancel != nil {
+		c.cancel()
+		<-c.exited
+	}
+	return nil
+}
+
+// Set stores value under key. A key found in a ghost list adapts the target
+// size of T1, as an ARC miss does, and enters T2.
+func (c *Cache) Set(key string, value interface{}, ttl time.Duration) {
+	var expires int64
+	if ttl == DefaultExpiration {
+		ttl = c.ttl
+	}
+	if ttl > 0 {
+		expires = time.Now().Add(ttl).UnixNano()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, exists := c.entries[key]
+	switch {
+	case !exists:
+		e = &entry{key: key}
+		if c.capacity > 0 {
+			c.makeRoom()
+		}
+		c.entries[key] = e
+		c.push(e, t1)
+	case e.list == t1 || e.list == t2:
+		c.move(e, t2)
+	default:
+		if e.list == b1 {
+			c.target = min(c.capacity, c.target+max(c.lists[b2].Len()/c.lists[b1].Len(), 1))
+		} else {
+			c.target = max(0, c.target-max(c.lists[b1].Len()/c.lists[b2].Len(), 1))
+		}
+		if c.full() {
+			c.replace(e.list == b2)
+		}
+		c.move(e, t2)
+	}
+	e.value, e.expires = value, expires
+}
+
+// makeRoom frees a slot for a key that is in none of the lists, trimming the
+// ghost lists so that T1+B1 and the four lists together stay within one and
+// two times the capacity.
+// The caller must hold c.mu.
+func (c *Cache) makeRoom() {
+	l1 := c.lists[t1].Len() + c.lists[b1].Len()
+	total := l1 + c.lists[t2].Len() + c.lists[b2].Len()
+	switch {
+	case l1 >= c.capacity:
+		if c.lists[t1].Len() < c.capacity {
+			c.drop(b1)
+			if c.full() {
+				c.replace(false)
+			}
+		} else {
+			c.evict(t1, -1)
+		}
+	case total >= c.capacity:
+		if total >= 2*c.capacity {
+			c.drop(b2)
+		}
+		if c.full() {
+			c.replace(false)
+		}
+	}
+}
+
+// replace evicts the least recently used item of T1 into B1 if T1 is longer
+// than its target, or of T2 into B2 otherwise. inB2 reports whether the key
+// being stored came from B2, which breaks the tie when T1 is at its target.
+// The caller must hold c.mu.
+func (c *Cache) replace(inB2 bool) {
+	n := c.lists[t1].Len()
+	if n > 0 && (n > c.target || (inB2 && n == c.target) || c.lists[t2].Len() == 0) {
+		c.evict(t1, b1)
+	} else {
+		c.evict(t2, b2)
+	}
+}
+
+// evict drops the value of the least recently used item of list from, and
+// moves its key to the ghost list to, or forgets it if to is -1.
+// The caller must hold c.mu.
+func (c *Cache) evict(from, to int) {
+	elem := c.lists[from].Back()
+	if elem == nil {
+		return
+	}
+	e := elem.Value.(*entry)
+	c.evictions++
+	if to < 0 {
+		c.remove(e)
+		return
+	}
+	e.value, e.expires = nil, 0
+	c.move(e, to)
+}
+
+// drop forgets the least recently used key of a ghost list.
+// The caller must hold c.mu.
+func (c *Cache) drop(ghost int) {
+	if elem := c.lists[ghost].Back(); elem != nil {
+		c.remove(elem.Value.(*entry))
+	}
+}
+
+// full reports whether T1 and T2 hold capacity items.
+func (c *Cache) full() bool {
+	return c.lists[t1].Len()+c.lists[t2].Len() >= c.capacity
+}
+
+func (c *Cache) push(e *entry, l int) {
+	e.list = l
+	e.elem = c.lists[l].PushFront(e)
+}
+
+// move makes e the most recently used entry of list l.
+func (c *Cache) move(e *entry, l int) {
+	c.lists[e.list].Remove(e.elem)
+	c.push(e, l)
+}
+
+func (c *Cache) remove(e *entry) {
+	c.lists[e.list].Remove(e.elem)
+	delete(c.entries, e.key)
+}
+
+// SetMaxEntries bounds the cache to n items, chosen by ARC; n <= 0 removes the
+// bound and forgets the ghost lists. Shrinking evicts as Set would.
+func (c *Cache) SetMaxEntries(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.capacity = max(n, 0)
+	c.target = min(c.target, c.capacity)
+	if c.capacity == 0 {
+		for _, ghost := range []int{b1, b2} {
+			for c.lists[ghost].Len() > 0 {
+				c.drop(ghost)
+			}
+		}
+		return
+	}
+	for c.lists[t1].Len()+c.lists[t2].Len() > c.capacity {
+		c.replace(false)
+	}
+	for c.lists[t1].Len()+c.lists[b1].Len() > c.capacity && c.lists[b1].Len() > 0 {
+		c.drop(b1)
+	}
+	for len(c.entries) > 2*c.capacity && c.lists[b2].Len() > 0 {
+		c.drop(b2)
+	}
+}
+
+// Evictions returns how many items have been evicted to respect the bound
+// set by SetMaxEntries. Expired and deleted items are not counted.
+func (c *Cache) Evictions() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.evictions
+}
+
+// Get returns the value stored under key. A hit moves the item to the front
+// of T2.
+func (c *Cache) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, exists := c.entries[key]
+	if !exists || e.list == b1 || e.list == b2 {
+		return nil, false
+	}
+	if e.expires > 0 && time.Now().UnixNano() > e.expires {
+		c.remove(e)
+		return nil, false
+	}
+	c.move(e, t2)
+	return e.value, true
+}
+
+// Delete removes key, and forgets it if it is only in a ghost list.
+func (c *Cache) Delete(key string) {
+	c.mu.Lock()
+	if e, exists := c.entries[key]; exists {
+		c.remove(e)
+	}
+	c.mu.Unlock()
+}
+
+func (c *Cache) cleanExpired(ctx context.Context) {
+	defer close(c.exited)
+	ticker := time.NewTicker(c.ttl)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.clean()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *Cache) clean() {
+	now := time.Now().UnixNano()
+	c.mu.Lock()
+	for _, e := range c.entries {
+		if e.expires > 0 && now > e.expires {
+			c.remove(e)
+		}
+	}
+	c.mu.Unlock()
+}
+
+// Len returns the number of items in T1 and T2, including expired items not
+// yet removed.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lists[t1].Len() + c.lists[t2].Len()
+}
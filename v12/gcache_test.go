@@ -0,0 +1,220 @@
+package v12
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"strconv"
+	"testing"
+	"time"
+
+	"benchmark-gocache/conformance"
+)
+
+// checkInvariants fails t unless the lists of c respect ARC's bounds.
+func checkInvariants(t *testing.T, c *Cache) {
+	t.Helper()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := [4]int{}
+	for i, l := range c.lists {
+		n[i] = l.Len()
+	}
+	if sum := n[t1] + n[t2] + n[b1] + n[b2]; sum != len(c.entries) {
+		t.Fatalf("lists hold %d entries, map %d", sum, len(c.entries))
+	}
+	if c.capacity == 0 {
+		return
+	}
+	switch {
+	case n[t1]+n[t2] > c.capacity:
+		t.Fatalf("T1+T2 = %d, over capacity %d", n[t1]+n[t2], c.capacity)
+	case n[t1]+n[b1] > c.capacity:
+		t.Fatalf("T1+B1 = %d, over capacity %d", n[t1]+n[b1], c.capacity)
+	case len(c.entries) > 2*c.capacity:
+		t.Fatalf("directory holds %d keys, over twice the capacity %d", len(c.entries), c.capacity)
+	case c.target < 0 || c.target > c.capacity:
+		t.Fatalf("target = %d, outside [0, %d]", c.target, c.capacity)
+	}
+}
+
+func TestCache_SetAndGet(t *testing.T) {
+	cache := New(10 * time.Minute)
+	defer cache.Close()
+
+	cache.Set("key1", "value1", DefaultExpiration)
+	cache.Set("key2", 12345, DefaultExpiration)
+	if v, found := cache.Get("key1"); !found || v != "value1" {
+		t.Errorf("Get(key1) = %v, %v; want value1, true", v, found)
+	}
+	cache.Set("key2", 6789, DefaultExpiration)
+	if v, found := cache.Get("key2"); !found || v != 6789 {
+		t.Errorf("Get(key2) = %v, %v; want 6789, true", v, found)
+	}
+	cache.Delete("key1")
+	if _, found := cache.Get("key1"); found {
+		t.Errorf("Expected key1 to be deleted")
+	}
+	if n := cache.Len(); n != 1 {
+		t.Errorf("Len() = %d, want 1", n)
+	}
+}
+
+func TestCache_Expiration(t *testing.T) {
+	cache := New(50 * time.Millisecond)
+	defer cache.Close()
+
+	cache.Set("short", 1, DefaultExpiration)
+	cache.Set("forever", 2, NoExpiration)
+	time.Sleep(200 * time.Millisecond)
+	if n := cache.Len(); n != 1 {
+		t.Errorf("Len() after cleanup = %d, want 1", n)
+	}
+	if _, found := cache.Get("forever"); !found {
+		t.Errorf("Expected the item without expiration to be present")
+	}
+}
+
+func TestCache_MaxEntries(t *testing.T) {
+	cache := New(10 * time.Minute)
+	defer cache.Close()
+	cache.SetMaxEntries(100)
+
+	for i := 0; i < 1000; i++ {
+		cache.Set(strconv.Itoa(i), i, DefaultExpiration)
+	}
+	checkInvariants(t, cache)
+	if n := cache.Len(); n != 100 {
+		t.Errorf("Len() = %d, want 100", n)
+	}
+	if _, found := cache.Get("999"); !found {
+		t.Errorf("Expected the last key set to be present")
+	}
+	if n := cache.Evictions(); n != 900 {
+		t.Errorf("Evictions() = %d, want 900", n)
+	}
+
+	cache.SetMaxEntries(10)
+	checkInvariants(t, cache)
+	if n := cache.Len(); n != 10 {
+		t.Errorf("Len() after shrinking = %d, want 10", n)
+	}
+
+	cache.SetMaxEntries(0)
+	for i := 0; i < 1000; i++ {
+		cache.Set(strconv.Itoa(i), i, DefaultExpiration)
+	}
+	checkInvariants(t, cache)
+	if n := cache.Len(); n != 1000 {
+		t.Errorf("Len() after removing the bound = %d, want 1000", n)
+	}
+}
+
+func TestCache_ScanResistance(t *testing.T) {
+	const capacity = 100
+	cache := New(10 * time.Minute)
+	defer cache.Close()
+	cache.SetMaxEntries(capacity)
+
+	// Keys read twice move to T2; a scan of keys seen once only cycles T1.
+	for i := 0; i < capacity/2; i++ {
+		key := "hot-" + strconv.Itoa(i)
+		cache.Set(key, i, DefaultExpiration)
+		cache.Get(key)
+	}
+	for i := 0; i < 10*capacity; i++ {
+		cache.Set("scan-"+strconv.Itoa(i), i, DefaultExpiration)
+	}
+	checkInvariants(t, cache)
+	for i := 0; i < capacity/2; i++ {
+		if _, found := cache.Get("hot-" + strconv.Itoa(i)); !found {
+			t.Fatalf("hot-%d was flushed by the scan", i)
+		}
+	}
+}
+
+func TestCache_Adapts(t *testing.T) {
+	const capacity = 100
+	cache := New(10 * time.Minute)
+	defer cache.Close()
+	cache.SetMaxEntries(capacity)
+
+	// With half the cache in T2, a loop over more keys than T1 can hold
+	// keeps missing on keys of B1: T1 was too small, so its target grows.
+	for i := 0; i < capacity/2; i++ {
+		key := "t2-" + strconv.Itoa(i)
+		cache.Set(key, i, DefaultExpiration)
+		cache.Get(key)
+	}
+	for round := 0; round < 3; round++ {
+		for i := 0; i < capacity*3/4; i++ {
+			key := strconv.Itoa(i)
+			if _, found := cache.Get(key); !found {
+				cache.Set(key, i, DefaultExpiration)
+			}
+		}
+	}
+	checkInvariants(t, cache)
+	if cache.target == 0 {
+		t.Errorf("target = 0 after misses in B1, want it to grow")
+	}
+
+	// A miss in B2 shrinks it again. With T1 at most its target, replace
+	// demotes the least recently used key of T2.
+	cache.Set("frequent", 0, DefaultExpiration)
+	cache.Get("frequent")
+	cache.mu.Lock()
+	cache.target = capacity
+	cache.replace(false)
+	ghost := cache.lists[b2].Front().Value.(*entry).key
+	cache.mu.Unlock()
+
+	cache.Set(ghost, 0, DefaultExpiration)
+	checkInvariants(t, cache)
+	if cache.target >= capacity {
+		t.Errorf("target = %d after a miss in B2, want below %d", cache.target, capacity)
+	}
+	if _, found := cache.Get(ghost); !found {
+		t.Errorf("Expected %s to be stored again", ghost)
+	}
+}
+
+func TestCache_RandomOps(t *testing.T) {
+	cache := New(10 * time.Minute)
+	defer cache.Close()
+	cache.SetMaxEntries(50)
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100_000; i++ {
+		key := strconv.Itoa(int(r.ExpFloat64() * 40))
+		switch op := r.Intn(10); {
+		case op < 6:
+			if _, found := cache.Get(key); !found {
+				cache.Set(key, i, DefaultExpiration)
+			}
+		case op < 9:
+			cache.Set(key, i, DefaultExpiration)
+		default:
+			cache.Delete(key)
+		}
+		if i%1000 == 0 {
+			checkInvariants(t, cache)
+		}
+		if i == 50_000 {
+			cache.SetMaxEntries(20)
+		}
+	}
+	checkInvariants(t, cache)
+}
+
+func TestConformance(t *testing.T) {
+	conformance.Run(t, func(ttl time.Duration) conformance.Cache {
+		return New(ttl)
+	})
+}
+
+func TestLifecycle(t *testing.T) {
+	conformance.RunLifecycle(t, func(ctx context.Context, ttl time.Duration) io.Closer {
+		return NewWithContext(ctx, ttl)
+	})
+}
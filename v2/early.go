@@ -0,0 +1,46 @@
+package v2
+
+import (
+	"fmt"
+	"time"
+)
+
+// SetWithDelta is Set for a value that took delta to compute. With SetXFetch,
+// Gets use delta to expire the item early.
+func (c *Cache[K, V]) SetWithDelta(key K, val V, d, delta time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.items[key]; exists {
+		return fmt.Errorf("item with key '%v' already exists. Use Update() instead", key)
+	}
+	return c.add(key, val, d, delta)
+}
+
+// UpdateWithDelta is Update for a value that took delta to compute. Use it to
+// store a value recomputed after Get expired its item early.
+func (c *Cache[K, V]) UpdateWithDelta(key K, val V, d, delta time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.items[key]; !exists {
+		return fmt.Errorf("item with key '%v' does not exist", key)
+	}
+	return c.add(key, val, d, delta)
+}
+
+// SetXFetch makes Get report an item that is about to expire as expired, with
+// a probability that grows as its expiry nears and with its delta, so that a
+// single caller recomputes it while the others keep hitting; see package
+// xfetch. Items stored without a delta never expire early.
+// beta <= 0 turns it off; xfetch.DefaultBeta is a sensible value.
+func (c *Cache[K, V]) SetXFetch(beta float64) {
+	c.early.SetBeta(beta)
+}
+
+// SetTTLJitter shortens the ttl of each item stored afterwards by a random
+// fraction, up to fraction, of its length, so that items stored together
+// expire apart. 0 turns it off.
+func (c *Cache[K, V]) SetTTLJitter(fraction float64) {
+	c.early.SetJitter(fraction)
+}
@@ -1,9 +1,12 @@
 package v2
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
+
+	"benchmark-gocache/xfetch"
 )
 
 const (
@@ -14,14 +17,22 @@ const (
 type Item[V any] struct {
 	value   V
 	expires int64
+	delta   int64 // time the value took to compute, for SetXFetch
+}
+
+func (i *Item[V]) Value() V {
+	return i.value
 }
 
 type cache[K ~string, V any] struct {
 	mu         sync.RWMutex
 	items      map[K]*Item[V]
-	done       chan struct{}
+	cancel     context.CancelFunc
+	exited     chan struct{}
 	expTime    time.Duration
 	cleanupInt time.Duration
+	maxEntries int
+	early      xfetch.Settings
 }
 
 type Cache[K ~string, V any] struct {
@@ -33,21 +44,38 @@ func newCache[K ~string, V any](expTime, cleanupInt time.Duration, item map[K]*I
 		items:      item,
 		expTime:    expTime,
 		cleanupInt: cleanupInt,
-		done:       make(chan struct{}),
 	}
 }
 
 func New[K ~string, V any](expTime, cleanupTime time.Duration) *Cache[K, V] {
+	return NewWithContext[K, V](context.Background(), expTime, cleanupTime)
+}
+
+// NewWithContext is like New, but the cleanup goroutine also stops when ctx is done.
+func NewWithContext[K ~string, V any](ctx context.Context, expTime, cleanupTime time.Duration) *Cache[K, V] {
 	items := make(map[K]*Item[V])
 	c := newCache(expTime, cleanupTime, items)
 
 	if cleanupTime > 0 {
-		go c.cleanup()
+		ctx, c.cancel = context.WithCancel(ctx)
+		c.exited = make(chan struct{})
+		go c.cleanup(ctx)
 	}
 
 	return &Cache[K, V]{c}
 }
 
+// Close stops the cleanup goroutine and waits for it to return. It is safe to
+// call more than once; the cache stays usable, but expired items are then
+// only removed when read.
+func (c *Cache[K, V]) Close() error {
+	if c.cancel != nil {
+		c.cancel()
+		<-c.exited
+	}
+	return nil
+}
+
 func (c *Cache[K, V]) Set(key K, val V, d time.Duration) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -55,30 +83,56 @@ func (c *Cache[K, V]) Set(key K, val V, d time.Duration) error {
 	if _, exists := c.items[key]; exists {
 		return fmt.Errorf("item with key '%v' already exists. Use Update() instead", key)
 	}
-	return c.add(key, val, d)
+	return c.add(key, val, d, 0)
 }
 
 func (c *Cache[K, V]) SetDefault(key K, val V) error {
 	return c.Set(key, val, DefaultExpires)
 }
 
-func (c *Cache[K, V]) add(key K, val V, d time.Duration) error {
+func (c *Cache[K, V]) add(key K, val V, d, delta time.Duration) error {
 	exp := int64(0)
 	if d == DefaultExpires {
 		d = c.expTime
 	}
 	if d > 0 {
-		exp = time.Now().Add(d).UnixNano()
+		exp = time.Now().Add(c.early.TTL(d)).UnixNano()
 	}
 
 	if str, ok := any(val).(string); ok && str == "" {
 		return fmt.Errorf("value of type string cannot be empty")
 	}
 
-	c.items[key] = &Item[V]{value: val, expires: exp}
+	if c.maxEntries > 0 {
+		if _, exists := c.items[key]; !exists {
+			for len(c.items) >= c.maxEntries {
+				c.evict()
+			}
+		}
+	}
+	c.items[key] = &Item[V]{value: val, expires: exp, delta: int64(delta)}
 	return nil
 }
 
+// SetMaxEntries bounds the cache to n items; n <= 0 removes the bound.
+// When full, adding a new key evicts an arbitrary item.
+func (c *Cache[K, V]) SetMaxEntries(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.maxEntries = n
+	for n > 0 && len(c.items) > n {
+		c.evict()
+	}
+}
+
+func (c *cache[K, V]) evict() {
+	for k := range c.items {
+		delete(c.items, k)
+		return
+	}
+}
+
 func (c *Cache[K, V]) Get(key K) (*Item[V], error) {
 	c.mu.RLock()
 	item, exists := c.items[key]
@@ -88,9 +142,15 @@ func (c *Cache[K, V]) Get(key K) (*Item[V], error) {
 		return nil, fmt.Errorf("item with key '%v' not found", key)
 	}
 
-	if item.expires > 0 && time.Now().UnixNano() > item.expires {
-		c.Delete(key)
-		return nil, fmt.Errorf("item with key '%v' expired", key)
+	if item.expires > 0 {
+		now := time.Now().UnixNano()
+		if now > item.expires {
+			c.Delete(key)
+			return nil, fmt.Errorf("item with key '%v' expired", key)
+		}
+		if c.early.Early(now, item.expires, item.delta) {
+			return nil, fmt.Errorf("item with key '%v' expired", key)
+		}
 	}
 
 	return item, nil
@@ -103,7 +163,7 @@ func (c *Cache[K, V]) Update(key K, val V, d time.Duration) error {
 	if _, exists := c.items[key]; !exists {
 		return fmt.Errorf("item with key '%v' does not exist", key)
 	}
-	return c.add(key, val, d)
+	return c.add(key, val, d, 0)
 }
 
 func (c *Cache[K, V]) Delete(key K) error {
@@ -157,7 +217,7 @@ func (c *Cache[K, V]) MapToCache(m map[K]V, d time.Duration) {
 	defer c.mu.Unlock()
 
 	for k, v := range m {
-		c.add(k, v, d)
+		c.add(k, v, d, 0)
 	}
 }
 
@@ -169,7 +229,8 @@ func (c *Cache[K, V]) IsExpired(key K) bool {
 	return exists && item.expires > 0 && time.Now().UnixNano() > item.expires
 }
 
-func (c *cache[K, V]) cleanup() {
+func (c *cache[K, V]) cleanup(ctx context.Context) {
+	defer close(c.exited)
 	ticker := time.NewTicker(c.cleanupInt)
 	defer ticker.Stop()
 
@@ -177,7 +238,7 @@ func (c *cache[K, V]) cleanup() {
 		select {
 		case <-ticker.C:
 			c.DeleteExpired()
-		case <-c.done:
+		case <-ctx.Done():
 			return
 		}
 	}
@@ -1,8 +1,13 @@
 package v2
 
 import (
+	"context"
+	"io"
+	"strconv"
 	"testing"
 	"time"
+
+	"benchmark-gocache/conformance"
 )
 
 type TestStruct struct {
@@ -48,3 +53,73 @@ func TestCacheTimes(t *testing.T) {
 	tc.Set("c", 3, 20*time.Millisecond)
 	tc.Set("d", 4, 70*time.Millisecond)
 }
+
+func TestCacheMaxEntries(t *testing.T) {
+	tc := New[string, int](10*time.Minute, 0)
+	tc.SetMaxEntries(100)
+
+	for i := 0; i < 1000; i++ {
+		tc.Set(strconv.Itoa(i), i, DefaultExpires)
+	}
+	if n := tc.Count(); n != 100 {
+		t.Errorf("Count() = %d, want 100", n)
+	}
+	if _, err := tc.Get("999"); err != nil {
+		t.Error("Expected the last key set to be present:", err)
+	}
+
+	tc.SetMaxEntries(10)
+	if n := tc.Count(); n != 10 {
+		t.Errorf("Count() after shrinking = %d, want 10", n)
+	}
+}
+
+// conformanceCache adapts Cache to conformance.Cache. Set refuses existing
+// keys, so overwrites go through Update.
+type conformanceCache struct {
+	c *Cache[string, any]
+}
+
+func (c conformanceCache) Set(key string, value any, ttl time.Duration) {
+	if err := c.c.Set(key, value, ttl); err != nil {
+		c.c.Update(key, value, ttl)
+	}
+}
+
+func (c conformanceCache) Get(key string) (any, bool) {
+	item, err := c.c.Get(key)
+	if err != nil {
+		return nil, false
+	}
+	return item.Value(), true
+}
+
+func (c conformanceCache) Delete(key string) { c.c.Delete(key) }
+
+func (c conformanceCache) SetWithDelta(key string, value any, ttl, delta time.Duration) {
+	if err := c.c.SetWithDelta(key, value, ttl, delta); err != nil {
+		c.c.UpdateWithDelta(key, value, ttl, delta)
+	}
+}
+
+func (c conformanceCache) SetXFetch(beta float64) { c.c.SetXFetch(beta) }
+
+func (c conformanceCache) SetTTLJitter(fraction float64) { c.c.SetTTLJitter(fraction) }
+
+func TestConformance(t *testing.T) {
+	conformance.Run(t, func(ttl time.Duration) conformance.Cache {
+		return conformanceCache{c: New[string, any](ttl, ttl)}
+	})
+}
+
+func TestEarlyExpiration(t *testing.T) {
+	conformance.RunEarly(t, func(ttl time.Duration) conformance.EarlyCache {
+		return conformanceCache{c: New[string, any](ttl, ttl)}
+	})
+}
+
+func TestLifecycle(t *testing.T) {
+	conformance.RunLifecycle(t, func(ctx context.Context, ttl time.Duration) io.Closer {
+		return NewWithContext[string, any](ctx, ttl, ttl)
+	})
+}
@@ -1,8 +1,11 @@
 package v3
 
 import (
+	"context"
 	"sync"
 	"time"
+
+	"benchmark-gocache/xfetch"
 )
 
 const (
@@ -13,6 +16,7 @@ const (
 type Item struct {
 	value   interface{}
 	expires int64
+	delta   int64 // time the value took to compute, for SetXFetch
 }
 
 func (i *Item) isExpired() bool {
@@ -20,22 +24,30 @@ func (i *Item) isExpired() bool {
 }
 
 type Cache struct {
-	mu       sync.RWMutex
-	ttl      time.Duration
-	items    map[string]*Item
-	cleaner  *time.Ticker
-	stopChan chan struct{}
+	mu         sync.RWMutex
+	ttl        time.Duration
+	items      map[string]*Item
+	maxEntries int
+	early      xfetch.Settings
+	cancel     context.CancelFunc
+	exited     chan struct{}
 }
 
 func New(ttl time.Duration, cleanupInterval time.Duration) *Cache {
+	return NewWithContext(context.Background(), ttl, cleanupInterval)
+}
+
+// NewWithContext is like New, but the cleanup goroutine also stops when ctx is done.
+func NewWithContext(ctx context.Context, ttl time.Duration, cleanupInterval time.Duration) *Cache {
 	cache := &Cache{
-		ttl:      ttl,
-		items:    make(map[string]*Item),
-		stopChan: make(chan struct{}),
+		ttl:   ttl,
+		items: make(map[string]*Item),
 	}
 
 	if cleanupInterval > 0 {
-		cache.startCleanup(cleanupInterval)
+		ctx, cache.cancel = context.WithCancel(ctx)
+		cache.exited = make(chan struct{})
+		go cache.cleanup(ctx, cleanupInterval)
 	}
 
 	return cache
@@ -49,17 +61,47 @@ func calculateExpiration(ttl time.Duration) int64 {
 }
 
 func (c *Cache) Set(key string, value interface{}, ttl time.Duration) {
+	c.set(key, value, ttl, 0)
+}
+
+func (c *Cache) set(key string, value interface{}, ttl, delta time.Duration) {
 	if ttl == DefaultExpiration {
 		ttl = c.ttl
 	}
 	c.mu.Lock()
+	if c.maxEntries > 0 {
+		if _, exists := c.items[key]; !exists {
+			for len(c.items) >= c.maxEntries {
+				c.evict()
+			}
+		}
+	}
 	c.items[key] = &Item{
 		value:   value,
-		expires: calculateExpiration(ttl),
+		expires: calculateExpiration(c.early.TTL(ttl)),
+		delta:   int64(delta),
 	}
 	c.mu.Unlock()
 }
 
+// SetMaxEntries bounds the cache to n items; n <= 0 removes the bound.
+// When full, Set evicts an arbitrary item to make room for a new key.
+func (c *Cache) SetMaxEntries(n int) {
+	c.mu.Lock()
+	c.maxEntries = n
+	for n > 0 && len(c.items) > n {
+		c.evict()
+	}
+	c.mu.Unlock()
+}
+
+func (c *Cache) evict() {
+	for key := range c.items {
+		delete(c.items, key)
+		return
+	}
+}
+
 func (c *Cache) Get(key string) (interface{}, bool) {
 	c.mu.RLock()
 	item, exists := c.items[key]
@@ -69,6 +111,9 @@ func (c *Cache) Get(key string) (interface{}, bool) {
 		c.Delete(key) // Remove itens expirados
 		return nil, false
 	}
+	if item.expires > 0 && c.early.Early(time.Now().UnixNano(), item.expires, item.delta) {
+		return nil, false
+	}
 	return item.value, true
 }
 
@@ -78,19 +123,19 @@ func (c *Cache) Delete(key string) {
 	c.mu.Unlock()
 }
 
-func (c *Cache) startCleanup(interval time.Duration) {
-	c.cleaner = time.NewTicker(interval)
-	go func() {
-		for {
-			select {
-			case <-c.cleaner.C:
-				c.Clean()
-			case <-c.stopChan:
-				c.cleaner.Stop()
-				return
-			}
+func (c *Cache) cleanup(ctx context.Context, interval time.Duration) {
+	defer close(c.exited)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.Clean()
+		case <-ctx.Done():
+			return
 		}
-	}()
+	}
 }
 
 func (c *Cache) Clean() {
@@ -103,6 +148,25 @@ func (c *Cache) Clean() {
 	c.mu.Unlock()
 }
 
+// StopCleanup is Close without the error; it may also be called more than once.
 func (c *Cache) StopCleanup() {
-	close(c.stopChan)
+	c.Close()
+}
+
+// Close stops the cleanup goroutine and waits for it to return. It is safe to
+// call more than once; the cache stays usable, but expired items are then
+// only removed when read or by Clean.
+func (c *Cache) Close() error {
+	if c.cancel != nil {
+		c.cancel()
+		<-c.exited
+	}
+	return nil
+}
+
+func (c *Cache) Len() int {
+	c.mu.RLock()
+	n := len(c.items)
+	c.mu.RUnlock()
+	return n
 }
@@ -1,8 +1,13 @@
 package v3
 
 import (
+	"context"
+	"io"
+	"strconv"
 	"testing"
 	"time"
+
+	"benchmark-gocache/conformance"
 )
 
 func TestCache_SetAndGet(t *testing.T) {
@@ -91,3 +96,49 @@ func TestCache_Concurrency(t *testing.T) {
 
 	t.Logf("Final value: %v", val) // Apenas para visualização do último valor salvo
 }
+
+func TestCache_MaxEntries(t *testing.T) {
+	cache := New(10*time.Minute, 0)
+	cache.SetMaxEntries(100)
+
+	for i := 0; i < 1000; i++ {
+		cache.Set(strconv.Itoa(i), i, DefaultExpiration)
+	}
+	if n := cache.Len(); n != 100 {
+		t.Errorf("Len() = %d, want 100", n)
+	}
+	if _, found := cache.Get("999"); !found {
+		t.Errorf("Expected the last key set to be present")
+	}
+
+	cache.SetMaxEntries(10)
+	if n := cache.Len(); n != 10 {
+		t.Errorf("Len() after shrinking = %d, want 10", n)
+	}
+
+	cache.SetMaxEntries(0)
+	for i := 0; i < 1000; i++ {
+		cache.Set(strconv.Itoa(i), i, DefaultExpiration)
+	}
+	if n := cache.Len(); n != 1000 {
+		t.Errorf("Len() after removing the bound = %d, want 1000", n)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	conformance.Run(t, func(ttl time.Duration) conformance.Cache {
+		return New(ttl, ttl)
+	})
+}
+
+func TestEarlyExpiration(t *testing.T) {
+	conformance.RunEarly(t, func(ttl time.Duration) conformance.EarlyCache {
+		return New(ttl, ttl)
+	})
+}
+
+func TestLifecycle(t *testing.T) {
+	conformance.RunLifecycle(t, func(ctx context.Context, ttl time.Duration) io.Closer {
+		return NewWithContext(ctx, ttl, ttl)
+	})
+}
@@ -1,8 +1,12 @@
 package v4
 
 import (
+	"context"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"benchmark-gocache/xfetch"
 )
 
 const (
@@ -13,36 +17,160 @@ const (
 type Item struct {
 	value   interface{}
 	expires int64
+	delta   int64  // time the value took to compute, for SetXFetch
+	seq     uint64 // insertion of the key the item belongs to, for fifo
+}
+
+// fifoEntry records the insertion of key with sequence number seq. It is
+// stale once the key is deleted, even if it is set again later.
+type fifoEntry struct {
+	key string
+	seq uint64
 }
 
 type Cache struct {
-	items sync.Map
-	ttl   time.Duration
+	items      sync.Map
+	ttl        time.Duration
+	count      atomic.Int64
+	maxEntries atomic.Int64
+	early      xfetch.Settings
+
+	// fifo records insertions in order while the cache is bounded. It may
+	// hold stale entries for keys that were since deleted or expired;
+	// eviction skips them. seq numbers the insertions.
+	fifoMu sync.Mutex
+	fifo   []fifoEntry
+	seq    uint64
+
+	cancel context.CancelFunc
+	exited chan struct{}
 }
 
 func New(ttl time.Duration) *Cache {
+	return NewWithContext(context.Background(), ttl)
+}
+
+// NewWithContext is like New, but the cleanup goroutine also stops when ctx is done.
+func NewWithContext(ctx context.Context, ttl time.Duration) *Cache {
 	c := &Cache{
 		ttl: ttl,
 	}
 	if ttl > 0 {
-		go c.cleanExpired()
+		ctx, c.cancel = context.WithCancel(ctx)
+		c.exited = make(chan struct{})
+		go c.cleanExpired(ctx)
 	}
 	return c
 }
 
+// Close stops the cleanup goroutine and waits for it to return. It is safe to
+// call more than once; the cache stays usable, but expired items are then
+// only removed when read.
+func (c *Cache) Close() error {
+	if c.cancel != nil {
+		c.cancel()
+		<-c.exited
+	}
+	return nil
+}
+
 func (c *Cache) Set(key string, value interface{}, ttl time.Duration) {
+	c.set(key, value, ttl, 0)
+}
+
+func (c *Cache) set(key string, value interface{}, ttl, delta time.Duration) {
 	var expires int64
 	if ttl == DefaultExpiration {
 		ttl = c.ttl
 	}
 	if ttl > 0 {
-		expires = time.Now().Add(ttl).UnixNano()
+		expires = time.Now().Add(c.early.TTL(ttl)).UnixNano()
 	}
 
-	c.items.Store(key, &Item{
+	item := &Item{
 		value:   value,
 		expires: expires,
-	})
+		delta:   int64(delta),
+	}
+	if c.maxEntries.Load() <= 0 {
+		if _, loaded := c.items.Swap(key, item); !loaded {
+			c.count.Add(1)
+		}
+		return
+	}
+
+	// A replaced item keeps its place in the fifo; a new one is numbered
+	// before it is visible, so that trim only evicts this insertion.
+	c.fifoMu.Lock()
+	defer c.fifoMu.Unlock()
+	if prev, ok := c.items.Load(key); ok {
+		item.seq = prev.(*Item).seq
+	} else {
+		c.seq++
+		item.seq = c.seq
+	}
+	if _, loaded := c.items.Swap(key, item); !loaded {
+		c.count.Add(1)
+		c.fifo = append(c.fifo, fifoEntry{key, item.seq})
+		c.trim()
+	}
+}
+
+// SetMaxEntries bounds the cache to n items; n <= 0 removes the bound.
+// When full, Set evicts the oldest inserted keys first (FIFO). Concurrent
+// writers may briefly overshoot the bound.
+func (c *Cache) SetMaxEntries(n int) {
+	c.fifoMu.Lock()
+	defer c.fifoMu.Unlock()
+
+	c.maxEntries.Store(int64(n))
+	if n <= 0 {
+		c.fifo = nil
+		return
+	}
+	if len(c.fifo) == 0 {
+		c.items.Range(func(key, value interface{}) bool {
+			c.fifo = append(c.fifo, fifoEntry{key.(string), value.(*Item).seq})
+			return true
+		})
+	}
+	c.trim()
+}
+
+// trim evicts the oldest keys until the cache is within its bound.
+// The caller must hold c.fifoMu.
+func (c *Cache) trim() {
+	max := c.maxEntries.Load()
+	for c.count.Load() > max && len(c.fifo) > 0 {
+		e := c.fifo[0]
+		c.fifo[0] = fifoEntry{}
+		c.fifo = c.fifo[1:]
+		if item, ok := c.current(e); ok && c.items.CompareAndDelete(e.key, item) {
+			c.count.Add(-1)
+		}
+	}
+
+	// Drop stale entries so fifo stays proportional to the bound.
+	if int64(len(c.fifo)) > 2*max {
+		live := c.fifo[:0]
+		for _, e := range c.fifo {
+			if _, ok := c.current(e); ok {
+				live = append(live, e)
+			}
+		}
+		clear(c.fifo[len(live):])
+		c.fifo = live
+	}
+}
+
+// current returns the item stored by the insertion e records, unless its key
+// was deleted since.
+func (c *Cache) current(e fifoEntry) (interface{}, bool) {
+	item, ok := c.items.Load(e.key)
+	if !ok || item.(*Item).seq != e.seq {
+		return nil, false
+	}
+	return item, true
 }
 
 func (c *Cache) Get(key string) (interface{}, bool) {
@@ -52,28 +180,46 @@ func (c *Cache) Get(key string) (interface{}, bool) {
 	}
 
 	item := val.(*Item)
-	if item.expires > 0 && time.Now().UnixNano() > item.expires {
-		c.items.Delete(key)
-		return nil, false
+	if item.expires > 0 {
+		now := time.Now().UnixNano()
+		if now > item.expires {
+			c.Delete(key)
+			return nil, false
+		}
+		if c.early.Early(now, item.expires, item.delta) {
+			return nil, false
+		}
 	}
 	return item.value, true
 }
 
 func (c *Cache) Delete(key string) {
-	c.items.Delete(key)
+	if _, loaded := c.items.LoadAndDelete(key); loaded {
+		c.count.Add(-1)
+	}
 }
 
-func (c *Cache) cleanExpired() {
+func (c *Cache) cleanExpired(ctx context.Context) {
+	defer close(c.exited)
 	ticker := time.NewTicker(c.ttl)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		c.items.Range(func(key, value interface{}) bool {
-			item := value.(*Item)
-			if item.expires > 0 && time.Now().UnixNano() > item.expires {
-				c.items.Delete(key)
-			}
-			return true
-		})
+	for {
+		select {
+		case <-ticker.C:
+			c.items.Range(func(key, value interface{}) bool {
+				item := value.(*Item)
+				if item.expires > 0 && time.Now().UnixNano() > item.expires {
+					c.Delete(key.(string))
+				}
+				return true
+			})
+		case <-ctx.Done():
+			return
+		}
 	}
 }
+
+func (c *Cache) Len() int {
+	return int(c.count.Load())
+}
@@ -1,9 +1,14 @@
 package v4
 
 import (
+	"context"
+	"io"
+	"strconv"
 	"sync"
 	"testing"
 	"time"
+
+	"benchmark-gocache/conformance"
 )
 
 func TestCache_SetAndGet(t *testing.T) {
@@ -92,3 +97,74 @@ func TestCache_Concurrency(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestCache_MaxEntries(t *testing.T) {
+	cache := New(10 * time.Minute)
+	cache.SetMaxEntries(100)
+
+	for i := 0; i < 1000; i++ {
+		cache.Set(strconv.Itoa(i), i, DefaultExpiration)
+	}
+	if n := cache.Len(); n != 100 {
+		t.Errorf("Len() = %d, want 100", n)
+	}
+	if _, found := cache.Get("999"); !found {
+		t.Errorf("Expected the last key set to be present")
+	}
+
+	cache.SetMaxEntries(10)
+	if n := cache.Len(); n != 10 {
+		t.Errorf("Len() after shrinking = %d, want 10", n)
+	}
+
+	cache.SetMaxEntries(0)
+	for i := 0; i < 1000; i++ {
+		cache.Set(strconv.Itoa(i), i, DefaultExpiration)
+	}
+	if n := cache.Len(); n != 1000 {
+		t.Errorf("Len() after removing the bound = %d, want 1000", n)
+	}
+}
+
+// TestCache_MaxEntriesReinsert checks that a deleted key set again is evicted
+// as a new insertion, after the keys set before it.
+func TestCache_MaxEntriesReinsert(t *testing.T) {
+	cache := New(10 * time.Minute)
+	defer cache.Close()
+	cache.SetMaxEntries(2)
+	cache.Set("a", 1, DefaultExpiration)
+	cache.Set("b", 2, DefaultExpiration)
+	cache.Delete("a")
+	cache.Set("a", 3, DefaultExpiration)
+	cache.Set("c", 4, DefaultExpiration)
+
+	if _, found := cache.Get("b"); found {
+		t.Errorf("Expected b, the oldest insertion, to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, found := cache.Get(key); !found {
+			t.Errorf("Expected %q to survive the eviction", key)
+		}
+	}
+	if n := cache.Len(); n != 2 {
+		t.Errorf("Len() = %d, want 2", n)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	conformance.Run(t, func(ttl time.Duration) conformance.Cache {
+		return New(ttl)
+	})
+}
+
+func TestEarlyExpiration(t *testing.T) {
+	conformance.RunEarly(t, func(ttl time.Duration) conformance.EarlyCache {
+		return New(ttl)
+	})
+}
+
+func TestLifecycle(t *testing.T) {
+	conformance.RunLifecycle(t, func(ctx context.Context, ttl time.Duration) io.Closer {
+		return NewWithContext(ctx, ttl)
+	})
+}
@@ -0,0 +1,52 @@
+package v5
+
+import "benchmark-gocache/policy"
+
+// SetEvictionPolicy bounds the cache to about n items, split evenly across
+// shards, and lets a policy built by newPolicy for each shard, such as
+// policy.NewLRU, pick the item a full shard evicts. n <= 0 or a nil newPolicy
+// removes the bound. Items already stored are handed to the policy in
+// arbitrary order.
+func (c *Cache) SetEvictionPolicy(n int, newPolicy policy.Factory) {
+	per := 0
+	if n > 0 && newPolicy != nil {
+		per = (n + shardCount - 1) / shardCount
+	}
+	for _, sh := range c.shards {
+		sh.mu.Lock()
+		sh.maxEntries = per
+		sh.setPolicy(nil)
+		if per > 0 {
+			p := newPolicy(per)
+			for key := range sh.items {
+				p.OnInsert(key)
+			}
+			sh.setPolicy(p)
+		}
+		for per > 0 && len(sh.items) > per {
+			sh.evict()
+		}
+		sh.unlock()
+	}
+}
+
+func (sh *shard) setPolicy(p policy.EvictionPolicy) {
+	sh.policyMu.Lock()
+	sh.policy = p
+	sh.policyMu.Unlock()
+}
+
+// notify checks sh.policy again: Get calls it after releasing sh.mu.
+func (sh *shard) notify(event func(policy.EvictionPolicy, string), key string) {
+	sh.policyMu.Lock()
+	if sh.policy != nil {
+		event(sh.policy, key)
+	}
+	sh.policyMu.Unlock()
+}
+
+func (sh *shard) victim() (string, bool) {
+	sh.policyMu.Lock()
+	defer sh.policyMu.Unlock()
+	return sh.policy.Victim()
+}
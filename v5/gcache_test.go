@@ -1,9 +1,15 @@
 package v5
 
 import (
+	"context"
+	"io"
+	"strconv"
 	"sync"
 	"testing"
 	"time"
+
+	"benchmark-gocache/conformance"
+	"benchmark-gocache/policy"
 )
 
 func TestCache_SetAndGet(t *testing.T) {
@@ -92,3 +98,92 @@ func TestCache_Concurrency(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestCache_MaxEntries(t *testing.T) {
+	cache := New(10 * time.Minute)
+	cache.SetMaxEntries(100)
+
+	for i := 0; i < 1000; i++ {
+		cache.Set(strconv.Itoa(i), i, DefaultExpiration)
+	}
+	if n := cache.Len(); n > 100+shardCount {
+		t.Errorf("Len() = %d, want at most %d", n, 100+shardCount)
+	}
+	if _, found := cache.Get("999"); !found {
+		t.Errorf("Expected the last key set to be present")
+	}
+
+	cache.SetMaxEntries(10)
+	if n := cache.Len(); n > 10+shardCount {
+		t.Errorf("Len() after shrinking = %d, want at most %d", n, 10+shardCount)
+	}
+
+	cache.SetMaxEntries(0)
+	for i := 0; i < 1000; i++ {
+		cache.Set(strconv.Itoa(i), i, DefaultExpiration)
+	}
+	if n := cache.Len(); n != 1000 {
+		t.Errorf("Len() after removing the bound = %d, want 1000", n)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	conformance.Run(t, func(ttl time.Duration) conformance.Cache {
+		return New(ttl)
+	})
+}
+
+func TestEarlyExpiration(t *testing.T) {
+	conformance.RunEarly(t, func(ttl time.Duration) conformance.EarlyCache {
+		return New(ttl)
+	})
+}
+
+func TestEvictCallbacks(t *testing.T) {
+	conformance.RunEvict(t, func(ttl time.Duration) conformance.EvictCache {
+		return New(ttl)
+	})
+}
+
+func TestLifecycle(t *testing.T) {
+	conformance.RunLifecycle(t, func(ctx context.Context, ttl time.Duration) io.Closer {
+		return NewWithContext(ctx, ttl)
+	})
+}
+
+func TestCache_EvictionPolicy(t *testing.T) {
+	for _, name := range policy.Names() {
+		t.Run(name, func(t *testing.T) {
+			newPolicy, _ := policy.Lookup(name)
+			cache := New(10 * time.Minute)
+			defer cache.Close()
+			cache.SetEvictionPolicy(4*shardCount, newPolicy)
+
+			cache.Set("hot", 0, DefaultExpiration)
+			for i := 0; i < 2000; i++ {
+				_, found := cache.Get("hot")
+				if !found && name != "fifo" && name != "random" {
+					t.Fatalf("hot key evicted after %d Sets", i)
+				}
+				cache.Set(strconv.Itoa(i), i, DefaultExpiration)
+				if i%10 == 0 {
+					cache.Delete(strconv.Itoa(i))
+				}
+			}
+			for i := 0; i < 1000; i++ {
+				cache.Set("more-"+strconv.Itoa(i), i, DefaultExpiration)
+			}
+			if n := cache.Len(); n != 4*shardCount {
+				t.Errorf("Len() = %d, want %d", n, 4*shardCount)
+			}
+
+			cache.SetMaxEntries(0)
+			for i := 0; i < 100; i++ {
+				cache.Set(strconv.Itoa(i), i, DefaultExpiration)
+			}
+			if n := cache.Len(); n < 100 {
+				t.Errorf("Len() after removing the bound = %d, want at least 100", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+package v5
+
+import (
+	"context"
+
+	"benchmark-gocache/loader"
+)
+
+// GetOrLoad returns the value stored under key or, on a miss, calls load and
+// stores the value it returns with the ttl it returns, as Set would.
+// Concurrent calls for the same key share a single call of load. Errors are
+// returned to every caller sharing the call and are only remembered if load
+// returns a ttl > 0 with them; see package loader.
+func (c *Cache) GetOrLoad(ctx context.Context, key string, load loader.Func) (interface{}, error) {
+	return c.loads.Load(ctx, c, key, load)
+}
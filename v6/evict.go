@@ -0,0 +1,23 @@
+package v6
+
+import "benchmark-gocache/policy"
+
+// OnEvict makes the cache call fn with every item it removes, and why: on
+// expiry, on Delete, when a Set replaces it, or to stay within the bound set
+// by SetMaxEntries. fn runs on the goroutine that removed
+// the item, after it unlocked the item's shard, so fn may call back into the
+// cache; it must be safe for concurrent use. A nil fn removes the callback.
+func (c *Cache) OnEvict(fn func(key string, value any, reason policy.EvictReason)) {
+	for _, sh := range c.shards {
+		sh.mu.Lock()
+		sh.evictions.SetFunc(fn)
+		sh.mu.Unlock()
+	}
+}
+
+// unlock releases sh.mu, then reports the items removed while it was held.
+func (sh *shard) unlock() {
+	evicted := sh.evictions.Take()
+	sh.mu.Unlock()
+	evicted.Report()
+}
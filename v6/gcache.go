@@ -1,9 +1,13 @@
 package v6
 
 import (
+	"context"
 	"hash/fnv"
 	"sync"
 	"time"
+
+	"benchmark-gocache/policy"
+	"benchmark-gocache/xfetch"
 )
 
 const (
@@ -15,29 +19,53 @@ const (
 type Item struct {
 	value   interface{}
 	expires int64
+	delta   int64 // time the value took to compute, for SetXFetch
 }
 
 type shard struct {
-	mu    sync.RWMutex
-	items map[string]*Item
+	mu         sync.RWMutex
+	items      map[string]*Item
+	maxEntries int
+	evictions  policy.Evictions
 }
 
 type Cache struct {
 	shards [shardCount]*shard
 	ttl    time.Duration
+	cancel context.CancelFunc
+	exited chan struct{}
+	early  xfetch.Settings
 }
 
 func New(ttl time.Duration) *Cache {
+	return NewWithContext(context.Background(), ttl)
+}
+
+// NewWithContext is like New, but the cleanup goroutine also stops when ctx is done.
+func NewWithContext(ctx context.Context, ttl time.Duration) *Cache {
 	c := &Cache{ttl: ttl}
 	for i := range c.shards {
 		c.shards[i] = &shard{items: make(map[string]*Item)}
 	}
 	if ttl > 0 {
-		go c.cleanExpired()
+		ctx, c.cancel = context.WithCancel(ctx)
+		c.exited = make(chan struct{})
+		go c.cleanExpired(ctx)
 	}
 	return c
 }
 
+// Close stops the cleanup goroutine and waits for it to return. It is safe to
+// call more than once; the cache stays usable, but expired items are then
+// only removed when read.
+func (c *Cache) Close() error {
+	if c.cancel != nil {
+		c.cancel()
+		<-c.exited
+	}
+	return nil
+}
+
 func (c *Cache) getShard(key string) *shard {
 	hash := fnv.New32a()
 	hash.Write([]byte(key))
@@ -46,18 +74,67 @@ func (c *Cache) getShard(key string) *shard {
 }
 
 func (c *Cache) Set(key string, value interface{}, ttl time.Duration) {
+	c.set(key, value, ttl, 0)
+}
+
+func (c *Cache) set(key string, value interface{}, ttl, delta time.Duration) {
 	var expires int64
 	if ttl == DefaultExpiration {
 		ttl = c.ttl
 	}
 	if ttl > 0 {
-		expires = time.Now().Add(ttl).UnixNano()
+		expires = time.Now().Add(c.early.TTL(ttl)).UnixNano()
 	}
 
 	sh := c.getShard(key)
 	sh.mu.Lock()
-	sh.items[key] = &Item{value: value, expires: expires}
-	sh.mu.Unlock()
+	old, exists := sh.items[key]
+	if sh.maxEntries > 0 && !exists {
+		for len(sh.items) >= sh.maxEntries {
+			sh.evict()
+		}
+	}
+	sh.items[key] = &Item{value: value, expires: expires, delta: int64(delta)}
+	if exists {
+		sh.evictions.Add(key, old.value, policy.Replaced)
+	}
+	sh.unlock()
+}
+
+// SetMaxEntries bounds the cache to about n items, split evenly across shards;
+// n <= 0 removes the bound. A full shard evicts an arbitrary item to make room
+// for a new key.
+func (c *Cache) SetMaxEntries(n int) {
+	per := 0
+	if n > 0 {
+		per = (n + shardCount - 1) / shardCount
+	}
+	for _, sh := range c.shards {
+		sh.mu.Lock()
+		sh.maxEntries = per
+		for per > 0 && len(sh.items) > per {
+			sh.evict()
+		}
+		sh.unlock()
+	}
+}
+
+// evict removes one item, chosen by Go's randomized map iteration.
+// The caller must hold sh.mu.
+func (sh *shard) evict() {
+	for key := range sh.items {
+		sh.remove(key, policy.Capacity)
+		return
+	}
+}
+
+// remove deletes key, and records it for the OnEvict callback with reason.
+// The caller must hold sh.mu.
+func (sh *shard) remove(key string, reason policy.EvictReason) {
+	if item, exists := sh.items[key]; exists {
+		delete(sh.items, key)
+		sh.evictions.Add(key, item.value, reason)
+	}
 }
 
 func (c *Cache) Get(key string) (interface{}, bool) {
@@ -70,9 +147,15 @@ func (c *Cache) Get(key string) (interface{}, bool) {
 		return nil, false
 	}
 
-	if item.expires > 0 && time.Now().UnixNano() > item.expires {
-		go c.Delete(key) // 🔥 Usa goroutine para deletar sem bloquear a leitura
-		return nil, false
+	if item.expires > 0 {
+		now := time.Now().UnixNano()
+		if now > item.expires {
+			go c.removeExpired(key, item) // 🔥 Usa goroutine para deletar sem bloquear a leitura
+			return nil, false
+		}
+		if c.early.Early(now, item.expires, item.delta) {
+			return nil, false
+		}
 	}
 
 	return item.value, true
@@ -81,24 +164,51 @@ func (c *Cache) Get(key string) (interface{}, bool) {
 func (c *Cache) Delete(key string) {
 	sh := c.getShard(key)
 	sh.mu.Lock()
-	delete(sh.items, key)
-	sh.mu.Unlock()
+	sh.remove(key, policy.Deleted)
+	sh.unlock()
 }
 
-func (c *Cache) cleanExpired() {
+// removeExpired removes key, which Get found expired, unless a Set replaced item
+// in the meantime.
+func (c *Cache) removeExpired(key string, item *Item) {
+	sh := c.getShard(key)
+	sh.mu.Lock()
+	if sh.items[key] == item {
+		sh.remove(key, policy.Expired)
+	}
+	sh.unlock()
+}
+
+func (c *Cache) cleanExpired(ctx context.Context) {
+	defer close(c.exited)
 	ticker := time.NewTicker(c.ttl)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		for _, sh := range c.shards {
-			sh.mu.Lock()
-			now := time.Now().UnixNano()
-			for key, item := range sh.items {
-				if item.expires > 0 && now > item.expires {
-					delete(sh.items, key)
+	for {
+		select {
+		case <-ticker.C:
+			for _, sh := range c.shards {
+				sh.mu.Lock()
+				now := time.Now().UnixNano()
+				for key, item := range sh.items {
+					if item.expires > 0 && now > item.expires {
+						sh.remove(key, policy.Expired)
+					}
 				}
+				sh.unlock()
 			}
-			sh.mu.Unlock()
+		case <-ctx.Done():
+			return
 		}
 	}
 }
+
+func (c *Cache) Len() int {
+	n := 0
+	for _, sh := range c.shards {
+		sh.mu.RLock()
+		n += len(sh.items)
+		sh.mu.RUnlock()
+	}
+	return n
+}
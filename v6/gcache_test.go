@@ -1,9 +1,14 @@
 package v6
 
 import (
+	"context"
+	"io"
+	"strconv"
 	"sync"
 	"testing"
 	"time"
+
+	"benchmark-gocache/conformance"
 )
 
 func TestCache_SetAndGet(t *testing.T) {
@@ -90,3 +95,55 @@ func TestCache_Concurrency(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestCache_MaxEntries(t *testing.T) {
+	cache := New(10 * time.Minute)
+	cache.SetMaxEntries(100)
+
+	for i := 0; i < 1000; i++ {
+		cache.Set(strconv.Itoa(i), i, DefaultExpiration)
+	}
+	if n := cache.Len(); n > 100+shardCount {
+		t.Errorf("Len() = %d, want at most %d", n, 100+shardCount)
+	}
+	if _, found := cache.Get("999"); !found {
+		t.Errorf("Expected the last key set to be present")
+	}
+
+	cache.SetMaxEntries(10)
+	if n := cache.Len(); n > 10+shardCount {
+		t.Errorf("Len() after shrinking = %d, want at most %d", n, 10+shardCount)
+	}
+
+	cache.SetMaxEntries(0)
+	for i := 0; i < 1000; i++ {
+		cache.Set(strconv.Itoa(i), i, DefaultExpiration)
+	}
+	if n := cache.Len(); n != 1000 {
+		t.Errorf("Len() after removing the bound = %d, want 1000", n)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	conformance.Run(t, func(ttl time.Duration) conformance.Cache {
+		return New(ttl)
+	})
+}
+
+func TestEarlyExpiration(t *testing.T) {
+	conformance.RunEarly(t, func(ttl time.Duration) conformance.EarlyCache {
+		return New(ttl)
+	})
+}
+
+func TestEvictCallbacks(t *testing.T) {
+	conformance.RunEvict(t, func(ttl time.Duration) conformance.EvictCache {
+		return New(ttl)
+	})
+}
+
+func TestLifecycle(t *testing.T) {
+	conformance.RunLifecycle(t, func(ctx context.Context, ttl time.Duration) io.Closer {
+		return NewWithContext(ctx, ttl)
+	})
+}
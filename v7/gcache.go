@@ -1,36 +1,66 @@
 package v7
 
 import (
+	"context"
 	"sync"
 	"time"
+
+	"benchmark-gocache/policy"
+	"benchmark-gocache/xfetch"
 )
 
 const (
 	NoExpiration      time.Duration = -1
 	DefaultExpiration time.Duration = 0
 	shardCount                      = 8
+	sweepBatch                      = 1024
 )
 
 type Item struct {
+	key     string // compared on lookup, so that colliding hashes can share a map slot
 	value   any
 	expires int64
+	delta   int64 // time the value took to compute, for SetXFetch
+	next    *Item // next item whose key has the same hash
+	pos     int   // index of the item in shard.order
 }
 
 type shard struct {
-	mu    sync.RWMutex
-	items map[uint32]*Item
+	mu         sync.RWMutex
+	items      map[uint32]*Item
+	order      []*Item // every item, so the sweeper can resume a scan by index after unlocking
+	maxEntries int
+	evictions  policy.Evictions
 }
 
 type Cache struct {
 	shards [shardCount]*shard
 	ttl    time.Duration
+	early  xfetch.Settings
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	sweepMu sync.Mutex
+	stop    chan struct{}
+	done    chan struct{}
 }
 
+// New starts a sweeper that runs every ttl/2 when ttl > 0; see SetSweeper.
 func New(ttl time.Duration) *Cache {
+	return NewWithContext(context.Background(), ttl)
+}
+
+// NewWithContext is like New, but the sweeper also stops, for good, when ctx
+// is done.
+func NewWithContext(ctx context.Context, ttl time.Duration) *Cache {
 	c := &Cache{ttl: ttl}
+	c.ctx, c.cancel = context.WithCancel(ctx)
 	for i := range c.shards {
 		c.shards[i] = &shard{items: make(map[uint32]*Item)}
 	}
+	if ttl > 0 {
+		c.SetSweeper(ttl/2, sweepBatch)
+	}
 	return c
 }
 
@@ -43,49 +73,268 @@ func hashKey(key string) uint32 {
 	return h
 }
 
-func (c *Cache) getShard(key string) *shard {
-	return c.shards[hashKey(key)%shardCount]
+func (c *Cache) Set(key string, value any, ttl time.Duration) {
+	c.set(key, value, ttl, 0)
 }
 
-func (c *Cache) Set(key string, value any, ttl time.Duration) {
+func (c *Cache) set(key string, value any, ttl, delta time.Duration) {
 	var expires int64
 	if ttl == DefaultExpiration {
 		ttl = c.ttl
 	}
 	if ttl > 0 {
-		expires = time.Now().Add(ttl).UnixNano()
+		expires = time.Now().Add(c.early.TTL(ttl)).UnixNano()
 	}
 
-	sh := c.getShard(key)
+	h := hashKey(key)
+	sh := c.shards[h%shardCount]
 	sh.mu.Lock()
-	sh.items[hashKey(key)] = &Item{
+	if sh.maxEntries > 0 {
+		if sh.lookup(h, key) == nil {
+			for len(sh.order) >= sh.maxEntries {
+				sh.evict()
+			}
+		}
+	}
+	sh.store(h, &Item{
+		key:     key,
 		value:   value,
 		expires: expires,
+		delta:   int64(delta),
+	})
+	sh.unlock()
+}
+
+// SetMaxEntries bounds the cache to about n items, split evenly across shards;
+// n <= 0 removes the bound. A full shard evicts an arbitrary item to make room
+// for a new key.
+func (c *Cache) SetMaxEntries(n int) {
+	per := 0
+	if n > 0 {
+		per = (n + shardCount - 1) / shardCount
+	}
+	for _, sh := range c.shards {
+		sh.mu.Lock()
+		sh.maxEntries = per
+		for per > 0 && len(sh.order) > per {
+			sh.evict()
+		}
+		sh.unlock()
+	}
+}
+
+// evict removes one item, chosen by Go's randomized map iteration.
+// The caller must hold sh.mu.
+func (sh *shard) evict() {
+	for h, item := range sh.items {
+		sh.remove(h, item.key, policy.Capacity)
+		return
+	}
+}
+
+// lookup returns the item stored under key, whose hash is h, or nil.
+// The caller must hold sh.mu.
+func (sh *shard) lookup(h uint32, key string) *Item {
+	for item := sh.items[h]; item != nil; item = item.next {
+		if item.key == key {
+			return item
+		}
+	}
+	return nil
+}
+
+// store links item into the chain for h, replacing the item with the same key.
+// The caller must hold sh.mu for writing.
+func (sh *shard) store(h uint32, item *Item) {
+	var prev *Item
+	for cur := sh.items[h]; cur != nil; prev, cur = cur, cur.next {
+		if cur.key == item.key {
+			item.next = cur.next
+			item.pos = cur.pos
+			sh.order[item.pos] = item
+			sh.link(h, prev, item)
+			sh.evictions.Add(cur.key, cur.value, policy.Replaced)
+			return
+		}
+	}
+	item.next = sh.items[h]
+	sh.items[h] = item
+	item.pos = len(sh.order)
+	sh.order = append(sh.order, item)
+}
+
+// remove unlinks the item stored under key, if any, and records it for the
+// OnEvict callback with reason.
+// The caller must hold sh.mu for writing.
+func (sh *shard) remove(h uint32, key string, reason policy.EvictReason) {
+	var prev *Item
+	for cur := sh.items[h]; cur != nil; prev, cur = cur, cur.next {
+		if cur.key == key {
+			if prev == nil && cur.next == nil {
+				delete(sh.items, h)
+			} else {
+				sh.link(h, prev, cur.next)
+			}
+			sh.unorder(cur)
+			sh.evictions.Add(cur.key, cur.value, reason)
+			return
+		}
+	}
+}
+
+// unorder swaps the last item of sh.order into item's place.
+func (sh *shard) unorder(item *Item) {
+	last := len(sh.order) - 1
+	moved := sh.order[last]
+	moved.pos = item.pos
+	sh.order[item.pos] = moved
+	sh.order[last] = nil
+	sh.order = sh.order[:last]
+	if cap(sh.order) > sweepBatch && len(sh.order) < cap(sh.order)/4 {
+		sh.order = append([]*Item(nil), sh.order...)
+	}
+}
+
+func (sh *shard) link(h uint32, prev, item *Item) {
+	if prev == nil {
+		sh.items[h] = item
+	} else {
+		prev.next = item
 	}
-	sh.mu.Unlock()
 }
 
 func (c *Cache) Get(key string) (any, bool) {
-	sh := c.getShard(key)
+	h := hashKey(key)
+	sh := c.shards[h%shardCount]
 	sh.mu.RLock()
-	item, exists := sh.items[hashKey(key)]
+	item := sh.lookup(h, key)
 	sh.mu.RUnlock()
 
-	if !exists {
+	if item == nil {
 		return nil, false
 	}
 
-	if item.expires > 0 && time.Now().UnixNano() > item.expires {
-		c.Delete(key)
-		return nil, false
+	if item.expires > 0 {
+		now := time.Now().UnixNano()
+		if now > item.expires {
+			c.removeExpired(h, item)
+			return nil, false
+		}
+		if c.early.Early(now, item.expires, item.delta) {
+			return nil, false
+		}
 	}
 
 	return item.value, true
 }
 
 func (c *Cache) Delete(key string) {
-	sh := c.getShard(key)
+	h := hashKey(key)
+	sh := c.shards[h%shardCount]
 	sh.mu.Lock()
-	delete(sh.items, hashKey(key))
-	sh.mu.Unlock()
+	sh.remove(h, key, policy.Deleted)
+	sh.unlock()
+}
+
+// removeExpired removes item, which Get found expired, unless a Set replaced it in
+// the meantime.
+func (c *Cache) removeExpired(h uint32, item *Item) {
+	sh := c.shards[h%shardCount]
+	sh.mu.Lock()
+	if sh.lookup(h, item.key) == item {
+		sh.remove(h, item.key, policy.Expired)
+	}
+	sh.unlock()
+}
+
+func (c *Cache) Len() int {
+	n := 0
+	for _, sh := range c.shards {
+		sh.mu.RLock()
+		n += len(sh.order)
+		sh.mu.RUnlock()
+	}
+	return n
+}
+
+// SetSweeper replaces the background sweeper with one that removes expired
+// items every interval; interval <= 0, or a closed cache, only stops the
+// current one. Each run
+// scans every shard, holding its write lock for at most batch items at a
+// time (sweepBatch if batch <= 0), so Sets and Gets are never blocked for a
+// full-map scan.
+func (c *Cache) SetSweeper(interval time.Duration, batch int) {
+	c.sweepMu.Lock()
+	defer c.sweepMu.Unlock()
+
+	if c.stop != nil {
+		close(c.stop)
+		<-c.done
+		c.stop, c.done = nil, nil
+	}
+	if interval <= 0 || c.ctx.Err() != nil {
+		return
+	}
+	if batch <= 0 {
+		batch = sweepBatch
+	}
+	c.stop, c.done = make(chan struct{}), make(chan struct{})
+	go c.sweepLoop(c.ctx, interval, batch, c.stop, c.done)
+}
+
+// StopSweeper stops the background sweeper, if one is running. Expired items
+// are then only removed when read.
+func (c *Cache) StopSweeper() {
+	c.SetSweeper(0, 0)
+}
+
+// Close stops the sweeper for good and waits for it to return. It is safe to
+// call more than once; the cache stays usable.
+func (c *Cache) Close() error {
+	c.cancel()
+	c.StopSweeper()
+	return nil
+}
+
+func (c *Cache) sweepLoop(ctx context.Context, interval time.Duration, batch int, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			now := time.Now().UnixNano()
+			for _, sh := range c.shards {
+				sh.sweep(now, batch)
+			}
+		case <-stop:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// sweep removes the items of sh that expired by now, batch items per lock.
+// Items moved by Sets and Deletes between batches may be skipped until the
+// next sweep.
+func (sh *shard) sweep(now int64, batch int) {
+	for i := 0; ; {
+		sh.mu.Lock()
+		for n := 0; n < batch && i < len(sh.order); n++ {
+			item := sh.order[i]
+			if item.expires > 0 && now > item.expires {
+				// The last item takes its place, so check index i again.
+				sh.remove(hashKey(item.key), item.key, policy.Expired)
+				continue
+			}
+			i++
+		}
+		done := i >= len(sh.order)
+		sh.unlock()
+		if done {
+			return
+		}
+	}
 }
@@ -1,9 +1,15 @@
 package v7
 
 import (
+	"context"
+	"io"
+	"runtime"
+	"strconv"
 	"sync"
 	"testing"
 	"time"
+
+	"benchmark-gocache/conformance"
 )
 
 func TestCache_SetAndGet(t *testing.T) {
@@ -90,3 +96,140 @@ func TestCache_Concurrency(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestCache_MaxEntries(t *testing.T) {
+	cache := New(10 * time.Minute)
+	cache.SetMaxEntries(100)
+
+	for i := 0; i < 1000; i++ {
+		cache.Set(strconv.Itoa(i), i, DefaultExpiration)
+	}
+	if n := cache.Len(); n > 100+shardCount {
+		t.Errorf("Len() = %d, want at most %d", n, 100+shardCount)
+	}
+	if _, found := cache.Get("999"); !found {
+		t.Errorf("Expected the last key set to be present")
+	}
+
+	cache.SetMaxEntries(10)
+	if n := cache.Len(); n > 10+shardCount {
+		t.Errorf("Len() after shrinking = %d, want at most %d", n, 10+shardCount)
+	}
+
+	cache.SetMaxEntries(0)
+	for i := 0; i < 1000; i++ {
+		cache.Set(strconv.Itoa(i), i, DefaultExpiration)
+	}
+	if n := cache.Len(); n != 1000 {
+		t.Errorf("Len() after removing the bound = %d, want 1000", n)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	conformance.Run(t, func(ttl time.Duration) conformance.Cache {
+		return New(ttl)
+	})
+}
+
+func TestEarlyExpiration(t *testing.T) {
+	conformance.RunEarly(t, func(ttl time.Duration) conformance.EarlyCache {
+		return New(ttl)
+	})
+}
+
+func TestEvictCallbacks(t *testing.T) {
+	conformance.RunEvict(t, func(ttl time.Duration) conformance.EvictCache {
+		return New(ttl)
+	})
+}
+
+// Keys whose FNV-1a hashes collide must not overwrite each other.
+func TestCache_HashCollision(t *testing.T) {
+	pairs := [][2]string{
+		{"key-375908", "key-1294886"},
+		{"key-375909", "key-1294887"},
+		{"a", "\x00a"},
+	}
+	for _, p := range pairs {
+		cache := New(time.Minute)
+		if hashKey(p[0]) != hashKey(p[1]) {
+			t.Fatalf("%q and %q do not collide", p[0], p[1])
+		}
+
+		cache.Set(p[0], "first", DefaultExpiration)
+		cache.Set(p[1], "second", DefaultExpiration)
+		if n := cache.Len(); n != 2 {
+			t.Errorf("Len() = %d, want 2", n)
+		}
+		if val, found := cache.Get(p[0]); !found || val != "first" {
+			t.Errorf("Get(%q) = %v, %v; want first, true", p[0], val, found)
+		}
+		if val, found := cache.Get(p[1]); !found || val != "second" {
+			t.Errorf("Get(%q) = %v, %v; want second, true", p[1], val, found)
+		}
+
+		cache.Set(p[0], "updated", DefaultExpiration)
+		cache.Delete(p[1])
+		if _, found := cache.Get(p[1]); found {
+			t.Errorf("Expected %q to be deleted", p[1])
+		}
+		if val, found := cache.Get(p[0]); !found || val != "updated" {
+			t.Errorf("Get(%q) = %v, %v; want updated, true", p[0], val, found)
+		}
+		if n := cache.Len(); n != 1 {
+			t.Errorf("Len() after Delete = %d, want 1", n)
+		}
+	}
+}
+
+func TestCache_Sweeper(t *testing.T) {
+	const n = 20_000
+	cache := New(time.Minute)
+	cache.SetSweeper(20*time.Millisecond, 100)
+	defer cache.StopSweeper()
+
+	for i := 0; i < n; i++ {
+		cache.Set(strconv.Itoa(i), i, 50*time.Millisecond)
+	}
+	cache.Set("forever", "value", NoExpiration)
+	cache.Set("long", "value", time.Minute)
+
+	for deadline := time.Now().Add(2 * time.Second); cache.Len() > 2; {
+		if time.Now().After(deadline) {
+			t.Fatalf("Len() = %d without reads, want the 2 unexpired items", cache.Len())
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	for _, key := range []string{"forever", "long"} {
+		if _, found := cache.Get(key); !found {
+			t.Errorf("Expected %q to survive the sweeper", key)
+		}
+	}
+}
+
+func TestCache_StopSweeper(t *testing.T) {
+	before := runtime.NumGoroutine()
+	cache := New(100 * time.Millisecond)
+	cache.SetSweeper(10*time.Millisecond, 0) // Replaces the sweeper New started
+	cache.Set("key1", "value1", 10*time.Millisecond)
+
+	cache.StopSweeper()
+	cache.StopSweeper()
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("%d goroutines after StopSweeper, want at most %d", n, before)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if n := cache.Len(); n != 1 {
+		t.Errorf("Len() = %d after stopping the sweeper, want 1", n)
+	}
+	if _, found := cache.Get("key1"); found {
+		t.Errorf("Expected key1 to be expired")
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	conformance.RunLifecycle(t, func(ctx context.Context, ttl time.Duration) io.Closer {
+		return NewWithContext(ctx, ttl)
+	})
+}
@@ -2,16 +2,26 @@ package v8
 
 import (
 	"container/heap"
+	"context"
 	"hash/fnv"
 	"sync"
 	"time"
+
+	"benchmark-gocache/policy"
+	"benchmark-gocache/xfetch"
+)
+
+const (
+	NoExpiration      time.Duration = -1
+	DefaultExpiration time.Duration = 0
 )
 
 type Item struct {
 	key     string
 	value   interface{}
 	expires int64
-	index   int // Indica a posição no heap
+	delta   int64 // time the value took to compute, for SetXFetch
+	index   int   // Indica a posição no heap
 }
 
 func (i *Item) isExpired() bool {
@@ -22,7 +32,15 @@ type PriorityQueue []*Item
 
 func (pq PriorityQueue) Len() int { return len(pq) }
 
+// Less orders items by expiration. Items that never expire (expires == 0)
+// sort last, so cleanup can stop at the first one.
 func (pq PriorityQueue) Less(i, j int) bool {
+	if pq[i].expires == 0 {
+		return false
+	}
+	if pq[j].expires == 0 {
+		return true
+	}
 	return pq[i].expires < pq[j].expires
 }
 
@@ -49,20 +67,29 @@ func (pq *PriorityQueue) Pop() interface{} {
 }
 
 type shard struct {
-	items map[string]*Item
-	mu    sync.RWMutex
-	pq    PriorityQueue
+	items      map[string]*Item
+	mu         sync.RWMutex
+	pq         PriorityQueue
+	maxEntries int
+	evictions  policy.Evictions
 }
 
 type Cache struct {
-	shards        []*shard
-	numShards     int
-	defaultTTL    time.Duration
-	cleanupTicker *time.Ticker
-	stopCleanup   chan struct{}
+	shards     []*shard
+	numShards  int
+	defaultTTL time.Duration
+	early      xfetch.Settings
+	cancel     context.CancelFunc
+	exited     chan struct{}
 }
 
 func New(ttl time.Duration, numShards int,
+	cleanupInterval ...time.Duration) *Cache {
+	return NewWithContext(context.Background(), ttl, numShards, cleanupInterval...)
+}
+
+// NewWithContext is like New, but the cleanup goroutine also stops when ctx is done.
+func NewWithContext(ctx context.Context, ttl time.Duration, numShards int,
 	cleanupInterval ...time.Duration) *Cache {
 	nShards := 8 // Padrão
 	if numShards > 0 {
@@ -75,11 +102,9 @@ func New(ttl time.Duration, numShards int,
 	}
 
 	c := &Cache{
-		numShards:     nShards,
-		defaultTTL:    ttl,
-		shards:        make([]*shard, nShards),
-		cleanupTicker: time.NewTicker(cleanupInt),
-		stopCleanup:   make(chan struct{}),
+		numShards:  nShards,
+		defaultTTL: ttl,
+		shards:     make([]*shard, nShards),
 	}
 
 	for i := 0; i < nShards; i++ {
@@ -90,31 +115,89 @@ func New(ttl time.Duration, numShards int,
 		heap.Init(&c.shards[i].pq)
 	}
 
-	go c.cleanupLoop()
+	// With neither a default ttl nor an interval there is no janitor;
+	// Get still hides expired items.
+	if cleanupInt > 0 {
+		ctx, c.cancel = context.WithCancel(ctx)
+		c.exited = make(chan struct{})
+		go c.cleanupLoop(ctx, cleanupInt)
+	}
 
 	return c
 }
 
+// Close stops the cleanup goroutine and waits for it to return. It is safe to
+// call more than once; the cache stays usable, but expired items are then
+// only removed when read.
+func (c *Cache) Close() error {
+	if c.cancel != nil {
+		c.cancel()
+		<-c.exited
+	}
+	return nil
+}
+
 func (c *Cache) getShard(key string) *shard {
 	hash := int(hashKey(key)) // Use uma hash function eficiente
 	return c.shards[hash%len(c.shards)]
 }
 
 func (c *Cache) Set(key string, value interface{}, ttl time.Duration) {
+	c.set(key, value, ttl, 0)
+}
+
+func (c *Cache) set(key string, value interface{}, ttl, delta time.Duration) {
+	if ttl == DefaultExpiration {
+		ttl = c.defaultTTL
+	}
 	sh := c.getShard(key)
-	expires := calculateExpiration(ttl)
-	item := &Item{key: key, value: value, expires: expires}
+	expires := calculateExpiration(c.early.TTL(ttl))
+	item := &Item{key: key, value: value, expires: expires, delta: int64(delta)}
 
 	sh.mu.Lock()
-	defer sh.mu.Unlock()
+	defer sh.unlock()
 
 	if oldItem := sh.items[key]; oldItem != nil {
 		heap.Remove(&sh.pq, oldItem.index)
+		sh.evictions.Add(key, oldItem.value, policy.Replaced)
+	} else if sh.maxEntries > 0 {
+		for len(sh.items) >= sh.maxEntries {
+			sh.evict()
+		}
 	}
 	sh.items[key] = item
 	heap.Push(&sh.pq, item)
 }
 
+// SetMaxEntries bounds the cache to about n items, split evenly across shards;
+// n <= 0 removes the bound. A full shard evicts an arbitrary item to make room
+// for a new key.
+func (c *Cache) SetMaxEntries(n int) {
+	per := 0
+	if n > 0 {
+		per = (n + c.numShards - 1) / c.numShards
+	}
+	for _, sh := range c.shards {
+		sh.mu.Lock()
+		sh.maxEntries = per
+		for per > 0 && len(sh.items) > per {
+			sh.evict()
+		}
+		sh.unlock()
+	}
+}
+
+// evict removes one item, chosen by Go's randomized map iteration.
+// The caller must hold sh.mu.
+func (sh *shard) evict() {
+	for key, item := range sh.items {
+		heap.Remove(&sh.pq, item.index)
+		delete(sh.items, key)
+		sh.evictions.Add(key, item.value, policy.Capacity)
+		return
+	}
+}
+
 func (c *Cache) Get(key string) (interface{}, bool) {
 	sh := c.getShard(key)
 	sh.mu.RLock()
@@ -124,26 +207,34 @@ func (c *Cache) Get(key string) (interface{}, bool) {
 	if !exists || item.isExpired() {
 		return nil, false
 	}
+	if item.expires > 0 && c.early.Early(time.Now().UnixNano(), item.expires, item.delta) {
+		return nil, false
+	}
 	return item.value, true
 }
 
 func (c *Cache) Delete(key string) {
 	sh := c.getShard(key)
 	sh.mu.Lock()
-	defer sh.mu.Unlock()
+	defer sh.unlock()
 
 	if item := sh.items[key]; item != nil {
 		heap.Remove(&sh.pq, item.index)
 		delete(sh.items, key)
+		sh.evictions.Add(key, item.value, policy.Deleted)
 	}
 }
 
-func (c *Cache) cleanupLoop() {
+func (c *Cache) cleanupLoop(ctx context.Context, interval time.Duration) {
+	defer close(c.exited)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
 	for {
 		select {
-		case <-c.cleanupTicker.C:
+		case <-ticker.C:
 			c.cleanup()
-		case <-c.stopCleanup:
+		case <-ctx.Done():
 			return
 		}
 	}
@@ -158,13 +249,14 @@ func (c *Cache) cleanup() {
 				break
 			}
 			min := sh.pq[0]
-			if min.expires > now {
+			if min.expires == 0 || min.expires > now {
 				break
 			}
 			heap.Pop(&sh.pq)
 			delete(sh.items, min.key)
+			sh.evictions.Add(min.key, min.value, policy.Expired)
 		}
-		sh.mu.Unlock()
+		sh.unlock()
 	}
 }
 
@@ -175,8 +267,18 @@ func hashKey(key string) uint32 {
 }
 
 func calculateExpiration(ttl time.Duration) int64 {
-	if ttl == 0 {
+	if ttl <= 0 {
 		return 0
 	}
 	return time.Now().Add(ttl).UnixNano()
 }
+
+func (c *Cache) Len() int {
+	n := 0
+	for _, sh := range c.shards {
+		sh.mu.RLock()
+		n += len(sh.items)
+		sh.mu.RUnlock()
+	}
+	return n
+}
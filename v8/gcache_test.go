@@ -1,9 +1,14 @@
 package v8
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"strconv"
 	"testing"
 	"time"
+
+	"benchmark-gocache/conformance"
 )
 
 func TestCache_SetAndGet(t *testing.T) {
@@ -84,3 +89,57 @@ func BenchmarkCache_Get(b *testing.B) {
 		cache.Get(fmt.Sprintf("key%d", i%100000))
 	}
 }
+
+func TestCache_MaxEntries(t *testing.T) {
+	cache := New(10*time.Minute, 8, time.Second)
+	cache.SetMaxEntries(100)
+
+	for i := 0; i < 1000; i++ {
+		cache.Set(strconv.Itoa(i), i, time.Minute)
+	}
+	if n := cache.Len(); n > 100+cache.numShards {
+		t.Errorf("Len() = %d, want at most %d", n, 100+cache.numShards)
+	}
+	if _, found := cache.Get("999"); !found {
+		t.Errorf("Expected the last key set to be present")
+	}
+
+	cache.SetMaxEntries(10)
+	if n := cache.Len(); n > 10+cache.numShards {
+		t.Errorf("Len() after shrinking = %d, want at most %d", n, 10+cache.numShards)
+	}
+
+	cache.SetMaxEntries(0)
+	for i := 0; i < 1000; i++ {
+		cache.Set(strconv.Itoa(i), i, time.Minute)
+	}
+	if n := cache.Len(); n != 1000 {
+		t.Errorf("Len() after removing the bound = %d, want 1000", n)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	conformance.Run(t, func(ttl time.Duration) conformance.Cache {
+		return New(ttl, 8, ttl)
+	})
+}
+
+func TestEarlyExpiration(t *testing.T) {
+	conformance.RunEarly(t, func(ttl time.Duration) conformance.EarlyCache {
+		return New(ttl, 8, ttl)
+	})
+}
+
+// Get leaves expired items to the cleanup goroutine, which reports them, so
+// it runs often enough for RunEvict to see the reports.
+func TestEvictCallbacks(t *testing.T) {
+	conformance.RunEvict(t, func(ttl time.Duration) conformance.EvictCache {
+		return New(ttl, 8, 10*time.Millisecond)
+	})
+}
+
+func TestLifecycle(t *testing.T) {
+	conformance.RunLifecycle(t, func(ctx context.Context, ttl time.Duration) io.Closer {
+		return NewWithContext(ctx, ttl, 8, ttl)
+	})
+}
@@ -0,0 +1,135 @@
+package v9
+
+import (
+	"reflect"
+	"time"
+)
+
+// Sizer returns the cost of a value, usually its size in bytes.
+// Supply one to SetSizer to measure your own types, such as structs that
+// hold strings or slices.
+type Sizer func(value interface{}) int64
+
+// DefaultSizer measures strings and byte slices by their length. Other
+// values cost the size of their type, without following pointers.
+func DefaultSizer(value interface{}) int64 {
+	switch v := value.(type) {
+	case nil:
+		return 0
+	case string:
+		return int64(len(v))
+	case []byte:
+		return int64(len(v))
+	}
+	return int64(reflect.TypeOf(value).Size())
+}
+
+// SetSizer makes the cache account for the cost of every item: the length of
+// its key plus the size returned by sizer for its value. Items already stored
+// are measured again, and evicted as needed to stay within SetMaxCost. A nil
+// sizer stops the accounting, unless SetMaxCost bounds the cache, in which
+// case DefaultSizer is used.
+func (c *Cache) SetSizer(sizer Sizer) {
+	for _, sh := range c.shards {
+		sh.mu.Lock()
+		if sizer == nil && sh.maxCost > 0 {
+			sh.resize(DefaultSizer)
+		} else {
+			sh.resize(sizer)
+		}
+		for sh.maxCost > 0 && sh.cost > sh.maxCost {
+			sh.evict()
+		}
+		sh.unlock()
+	}
+}
+
+// SetMaxCost bounds the total cost of the cache to about n, split evenly
+// across shards; n <= 0 removes the bound. When a Set would exceed its
+// shard's share, items are evicted, as chosen by the policy set with
+// SetTinyLFU or SetEvictionPolicy or otherwise arbitrarily, until it fits.
+// An item that costs more than a shard's share is not stored at all.
+// Costs are measured by DefaultSizer unless SetSizer provided another Sizer.
+func (c *Cache) SetMaxCost(n int64) {
+	var per int64
+	if n > 0 {
+		per = (n + numShards - 1) / numShards
+	}
+	for _, sh := range c.shards {
+		sh.mu.Lock()
+		sh.maxCost = per
+		if per > 0 && sh.sizer == nil {
+			sh.resize(DefaultSizer)
+		}
+		for per > 0 && sh.cost > per {
+			sh.evict()
+		}
+		sh.unlock()
+	}
+}
+
+// SetWithCost is like Set, but stores the item with the given cost instead of
+// the one computed by the Sizer. It reports whether the item was stored, which
+// it is not if its cost exceeds a shard's share of the bound set by
+// SetMaxCost; any previous item under key is then removed.
+func (c *Cache) SetWithCost(key string, value interface{}, cost int64, ttl time.Duration) bool {
+	return c.set(key, value, max(cost, 0), ttl, 0)
+}
+
+// Cost returns the total cost of the items stored, as measured by the Sizer
+// or given to SetWithCost. It is 0 if no cost has been set or measured.
+func (c *Cache) Cost() int64 {
+	var n int64
+	for _, sh := range c.shards {
+		sh.mu.RLock()
+		n += sh.cost
+		sh.mu.RUnlock()
+	}
+	return n
+}
+
+// measure sets the cost of item, unless it was given by SetWithCost.
+// The caller must hold sh.mu.
+func (sh *shard) measure(item *Item, cost int64) {
+	switch {
+	case cost >= 0:
+		item.cost = cost
+	case sh.sizer != nil:
+		item.cost = int64(len(item.key)) + sh.sizer(item.value)
+	}
+}
+
+// fit evicts items until item, which replaces any item stored under its key,
+// fits within the shard's cost bound. It reports false, evicting nothing, if
+// item alone exceeds the bound.
+// The caller must hold sh.mu for writing.
+func (sh *shard) fit(h uint32, item *Item) bool {
+	if item.cost > sh.maxCost {
+		return false
+	}
+	for {
+		var old int64
+		if cur := sh.lookup(h, item.key); cur != nil {
+			old = cur.cost
+		}
+		if sh.cost-old+item.cost <= sh.maxCost {
+			return true
+		}
+		sh.evict()
+	}
+}
+
+// resize measures every item again with sizer, or zeroes their costs if
+// sizer is nil, and makes it the shard's Sizer.
+// The caller must hold sh.mu for writing.
+func (sh *shard) resize(sizer Sizer) {
+	sh.sizer = sizer
+	sh.cost = 0
+	for _, head := range sh.items {
+		for item := head; item != nil; item = item.next {
+			item.cost = 0
+			sh.measure(item, -1)
+			sh.cost += item.cost
+		}
+	}
+}
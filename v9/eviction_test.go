@@ -0,0 +1,163 @@
+package v9
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"benchmark-gocache/policy"
+)
+
+func TestCache_EvictionPolicy(t *testing.T) {
+	// Policies that keep a key read between every two Sets.
+	keepsHot := map[string]bool{"lru": true, "lfu": true, "s3-fifo": true, "sieve": true}
+
+	for _, name := range policy.Names() {
+		t.Run(name, func(t *testing.T) {
+			newPolicy, _ := policy.Lookup(name)
+			cache := New(10 * time.Minute)
+			defer cache.Close()
+			cache.SetEvictionPolicy(10*numShards, newPolicy)
+
+			cache.Set("hot", 0, DefaultExpiration)
+			for i := 0; i < 1000; i++ {
+				if _, found := cache.Get("hot"); !found && keepsHot[name] {
+					t.Fatalf("hot key evicted after %d Sets", i)
+				}
+				cache.Set(strconv.Itoa(i), i, DefaultExpiration)
+			}
+			if n := cache.Len(); n != 10*numShards {
+				t.Errorf("Len() = %d, want %d", n, 10*numShards)
+			}
+
+			// Deleted and expired items leave the policy with the cache.
+			for i := 990; i < 1000; i++ {
+				cache.Delete(strconv.Itoa(i))
+			}
+			cache.Set("short", 1, time.Millisecond)
+			time.Sleep(5 * time.Millisecond)
+			cache.Get("short")
+			for i := 0; i < 1000; i++ {
+				cache.Set("more-"+strconv.Itoa(i), i, DefaultExpiration)
+			}
+			if n := cache.Len(); n != 10*numShards {
+				t.Errorf("Len() after Deletes = %d, want %d", n, 10*numShards)
+			}
+		})
+	}
+}
+
+func TestCache_EvictionPolicyReplaced(t *testing.T) {
+	cache := New(10 * time.Minute)
+	defer cache.Close()
+	for i := 0; i < 1000; i++ {
+		cache.Set(strconv.Itoa(i), i, DefaultExpiration)
+	}
+
+	// Items already stored are handed to the policy.
+	cache.SetEvictionPolicy(8*numShards, policy.NewLRU)
+	if n := cache.Len(); n != 8*numShards {
+		t.Errorf("Len() = %d, want %d", n, 8*numShards)
+	}
+
+	// Colliding keys are evicted by key, not by hash.
+	cache.SetEvictionPolicy(numShards, policy.NewLRU)
+	cache.Set("a", 1, DefaultExpiration)
+	cache.Set("\x00a", 2, DefaultExpiration)
+	if _, found := cache.Get("a"); found {
+		t.Errorf("Expected a to be evicted by \\x00a, which shares its hash")
+	}
+	if v, found := cache.Get("\x00a"); !found || v != 2 {
+		t.Errorf("Get(\\x00a) = %v, %v; want 2, true", v, found)
+	}
+
+	cache.SetTinyLFU(100)
+	cache.SetEvictionPolicy(0, policy.NewLRU)
+	for i := 0; i < 1000; i++ {
+		cache.Set(strconv.Itoa(i), i, DefaultExpiration)
+	}
+	if n := cache.Len(); n < 1000 {
+		t.Errorf("Len() after removing the bound = %d, want at least 1000", n)
+	}
+}
+
+func TestCache_EvictionPolicyConcurrent(t *testing.T) {
+	cache := New(10 * time.Minute)
+	defer cache.Close()
+	cache.SetEvictionPolicy(100, policy.NewS3FIFO)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				key := strconv.Itoa((g*31 + i) % 500)
+				if _, found := cache.Get(key); !found {
+					cache.Set(key, i, DefaultExpiration)
+				}
+				if i%7 == 0 {
+					cache.Delete(key)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if n, bound := cache.Len(), 100+numShards; n > bound {
+		t.Errorf("Len() = %d, want at most %d", n, bound)
+	}
+}
+
+func TestCache_OnEvictBounds(t *testing.T) {
+	bounds := map[string]func(c *Cache){
+		"tinylfu": func(c *Cache) { c.SetTinyLFU(10 * numShards) },
+		"lru":     func(c *Cache) { c.SetEvictionPolicy(10*numShards, policy.NewLRU) },
+		"cost":    func(c *Cache) { c.SetMaxCost(100 * numShards) },
+	}
+	for name, bound := range bounds {
+		t.Run(name, func(t *testing.T) {
+			cache := New(10 * time.Minute)
+			defer cache.Close()
+			bound(cache)
+			var mu sync.Mutex
+			evicted := 0
+			cache.OnEvict(func(key string, value any, reason policy.EvictReason) {
+				if reason != policy.Capacity {
+					t.Errorf("OnEvict(%q) reason = %v, want capacity", key, reason)
+				}
+				mu.Lock()
+				evicted++
+				mu.Unlock()
+			})
+
+			const keys = 1000
+			for i := 0; i < keys; i++ {
+				cache.SetWithCost(strconv.Itoa(i), i, 10, DefaultExpiration)
+			}
+			if n := cache.Len(); n+evicted != keys {
+				t.Errorf("Len() = %d with %d evictions reported, want %d in all", n, evicted, keys)
+			}
+		})
+	}
+
+	// A Set rejected for its cost removes, and reports, the previous item.
+	cache := New(10 * time.Minute)
+	defer cache.Close()
+	cache.SetMaxCost(100 * numShards)
+	cache.SetWithCost("key", "old", 10, DefaultExpiration)
+	var got []policy.EvictReason
+	cache.OnEvict(func(key string, value any, reason policy.EvictReason) {
+		if key != "key" || value != "old" {
+			t.Errorf("OnEvict(%q, %v), want key, old", key, value)
+		}
+		got = append(got, reason)
+	})
+	if cache.SetWithCost("key", "new", 1000, DefaultExpiration) {
+		t.Fatal("SetWithCost() of an item over the bound succeeded")
+	}
+	if len(got) != 1 || got[0] != policy.Capacity {
+		t.Errorf("OnEvict reasons = %v, want [capacity]", got)
+	}
+}
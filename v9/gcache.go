@@ -9,8 +9,14 @@
 package v9
 
 import (
+	"context"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"benchmark-gocache/loader"
+	"benchmark-gocache/policy"
+	"benchmark-gocache/xfetch"
 )
 
 const (
@@ -23,39 +29,83 @@ const (
 	// numShards defines the number of cache partitions to allow concurrent access.
 	numShards = 8
 
-	// ringSize sets the size of the expiration ring buffer for tracking expired items.
-	ringSize = 4096
-)
+	// sweepBatch bounds how many expired items cleanup removes per shard lock
+	// acquisition, so that a mass expiry does not stall Sets and Gets.
+	sweepBatch = 1024
 
-// ringNode represents an entry in the expiration ring buffer.
-type ringNode struct {
-	key     uint32 // Hashed key
-	expires int64  // Expiration timestamp in nanoseconds
-}
+	// sweepLimit bounds how many expired items cleanup removes per shard and
+	// tick; a mass expiry is spread over several ticks.
+	sweepLimit = 16 * sweepBatch
+
+	// shrinkMin is the smallest peak item count at which a shard rebuilds its
+	// maps once they are mostly empty.
+	shrinkMin = 1024
+)
 
 // shard is a partition of the cache with its own locking mechanism.
 type shard struct {
-	mu       sync.RWMutex     // Mutex for concurrent access
-	items    map[uint32]*Item // Cached items
-	ringBuf  []ringNode       // Ring buffer for tracking expiration
-	ringHead int              // Current position in the ring buffer
+	mu      sync.RWMutex     // Mutex for concurrent access
+	items   map[uint32]*Item // Cached items, chained by hash
+	buckets map[int64]*Item  // Items with a TTL, listed by the tick in which they expire
+	tick    int64            // Width of an expiry bucket in nanoseconds; 0 disables the index
+	swept   int64            // Buckets before this tick have been emptied by cleanup
+	count   int              // Number of items, including chained ones
+	peak    int              // Largest count since the maps were last rebuilt
+
+	maxEntries int      // Per-shard item limit; 0 means unbounded
+	lfu        *tinyLFU // W-TinyLFU policy; nil unless SetTinyLFU bounded the cache
+	cost       int64    // Total cost of the items
+	maxCost    int64    // Per-shard cost limit; 0 means unbounded
+	sizer      Sizer    // Measures the cost of values; nil disables the accounting
+
+	readMu sync.Mutex            // Guards reads and nReads, so Get can record hits under sh.mu.RLock
+	reads  [readBufSize]*lfuNode // Hits not yet applied to lfu
+	nReads int                   // Number of buffered hits
+
+	policyMu sync.Mutex              // Guards policy, so Get can report hits under sh.mu.RLock
+	policy   policy.EvictionPolicy   // Policy set by SetEvictionPolicy; nil if there is none
+	hash     func(key string) uint32 // Hashes the keys chosen by policy
+
+	evictions policy.Evictions // Items removed under sh.mu, for the OnEvict callback
 }
 
 // Item represents a single cache entry.
 type Item struct {
+	key     string      // Original key, compared on lookup to resolve hash collisions
+	hash    uint32      // Hash of key
 	value   interface{} // Stored value
 	expires int64       // Expiration timestamp
+	refresh int64       // Time from which a Get reloads the item in the background; 0 if never
+	delta   int64       // Time the value took to compute, for SetXFetch
+	next    *Item       // Next item whose key has the same hash
+	node    *lfuNode    // Policy node of the key, while the shard uses TinyLFU
+	cost    int64       // Cost of the item, if the shard accounts for costs
+
+	expPrev, expNext *Item // Neighbours in the expiry bucket
 }
 
 // Cache is a sharded in-memory cache with expiration handling.
 type Cache struct {
-	shards [numShards]*shard // Array of shards to reduce contention
-	ttl    time.Duration     // Default time-to-live for cache entries
+	shards [numShards]*shard  // Array of shards to reduce contention
+	ttl    time.Duration      // Default time-to-live for cache entries
+	cancel context.CancelFunc // Stops the cleanup goroutine; nil if there is none
+	exited chan struct{}      // Closed when the cleanup goroutine returns
+	loads  loader.Group       // Loads in flight for GetOrLoad and refreshes
+
+	refresher atomic.Pointer[refresher] // Set by SetRefresh; nil if there is none
+	early     xfetch.Settings           // Early expiration and ttl jitter settings
 }
 
 // New creates a new instance of Cache with the specified default TTL.
 // If the TTL is greater than 0, a cleanup goroutine is started to periodically remove expired items.
+// Call Close to stop it.
 func New(ttlStr ...time.Duration) *Cache {
+	return NewWithContext(context.Background(), ttlStr...)
+}
+
+// NewWithContext is like New, but ties the cleanup goroutine to ctx:
+// it stops when ctx is canceled or its deadline passes, as if Close were called.
+func NewWithContext(ctx context.Context, ttlStr ...time.Duration) *Cache {
 	var ttl time.Duration
 	if len(ttlStr) > 0 {
 		// Use the first duration provided
@@ -65,27 +115,47 @@ func New(ttlStr ...time.Duration) *Cache {
 		ttl = DefaultExpiration
 	}
 	c := &Cache{ttl: ttl}
+	// Expiry buckets are as wide as the cleanup interval, so each run
+	// usually empties a single bucket per shard.
+	var tick, now int64
+	if ttl > 0 {
+		tick = int64(ttl / 2)
+		now = time.Now().UnixNano()
+	}
 	for i := 0; i < numShards; i++ {
 		c.shards[i] = &shard{
 			items:   make(map[uint32]*Item),
-			ringBuf: make([]ringNode, ringSize),
+			buckets: make(map[int64]*Item),
+			tick:    tick,
+			hash:    c.hashKey,
+		}
+		if tick > 0 {
+			c.shards[i].swept = now / tick
 		}
 	}
 	if ttl > 0 {
-		go c.cleanup()
+		ctx, c.cancel = context.WithCancel(ctx)
+		c.exited = make(chan struct{})
+		go c.cleanup(ctx)
 	}
 	return c
 }
 
+// Close stops the cleanup goroutine and waits for it to return.
+// It is safe to call more than once, and concurrently. The cache remains usable
+// after Close, but expired items are then only removed when they are read.
+func (c *Cache) Close() error {
+	if c.cancel != nil {
+		c.cancel()
+		<-c.exited
+	}
+	return nil
+}
+
 // hashKey computes a simple FNV-1a hash from the string key.
 // The hash ensures even distribution across shards.
 func (c *Cache) hashKey(key string) uint32 {
-	var h uint32
-	for i := 0; i < len(key); i++ {
-		h ^= uint32(key[i])
-		h *= 16777619
-	}
-	return h
+	return HashString(key)
 }
 
 // getShard selects the shard based on the hashed key.
@@ -98,44 +168,92 @@ func (c *Cache) getShard(k uint32) *shard {
 // If `ttl` is set to `DefaultExpiration`, the cache's default TTL is applied.
 // If `ttl` is set to `NoExpiration`, the item never expires.
 func (c *Cache) Set(key string, value interface{}, ttl time.Duration) {
-	var exp int64
+	c.set(key, value, -1, ttl, 0)
+}
+
+// set stores value under key with the given cost, or the cost measured by the
+// shard's Sizer if cost < 0. It reports false if the item exceeds the cost
+// bound, after removing any previous item under key.
+func (c *Cache) set(key string, value interface{}, cost int64, ttl, delta time.Duration) bool {
+	var exp, refresh int64
 	if ttl == DefaultExpiration {
 		ttl = c.ttl
 	}
 	if ttl > 0 {
+		ttl = c.early.TTL(ttl)
 		exp = time.Now().Add(ttl).UnixNano()
+		if r := c.refresher.Load(); r != nil {
+			exp, refresh = r.deadlines(exp, ttl)
+		}
 	}
 
 	hashed := c.hashKey(key)
 	sh := c.getShard(hashed)
 
+	item := &Item{key: key, hash: hashed, value: value, expires: exp, refresh: refresh, delta: int64(delta)}
 	sh.mu.Lock()
-	sh.items[hashed] = &Item{value: value, expires: exp}
-	sh.ringBuf[sh.ringHead] = ringNode{key: hashed, expires: exp}
-	sh.ringHead = (sh.ringHead + 1) % ringSize
-	sh.mu.Unlock()
+	sh.measure(item, cost)
+	if sh.maxCost > 0 && !sh.fit(hashed, item) {
+		sh.remove(hashed, key, policy.Capacity)
+		sh.unlock()
+		return false
+	}
+	if sh.maxEntries > 0 {
+		if sh.lookup(hashed, key) == nil {
+			for sh.count >= sh.maxEntries {
+				sh.evict()
+			}
+		}
+	}
+	sh.store(hashed, item)
+	if sh.lfu != nil {
+		sh.admit(item)
+	}
+	sh.unlock()
+	return true
 }
 
 // Get retrieves a value from the cache.//
 // Returns the stored value and a boolean indicating if the key was found.
 // If the item has expired, it is removed from the cache and (nil, false) is returned.
+// With SetRefresh, a stale item is returned and reloaded in the background.
 func (c *Cache) Get(key string) (interface{}, bool) {
 	hashed := c.hashKey(key)
 	sh := c.getShard(hashed)
 
 	sh.mu.RLock()
-	item, exists := sh.items[hashed]
+	item := sh.lookup(hashed, key)
+	var node *lfuNode
+	if item != nil && sh.lfu != nil {
+		node = item.node
+	}
+	tracked := sh.policy != nil
 	sh.mu.RUnlock()
 
-	if !exists {
+	if item == nil {
 		return nil, false
 	}
 
-	if item.expires > 0 && time.Now().UnixNano() > item.expires {
-		c.Delete(key) // Remove expired item
-		return nil, false
+	if item.expires > 0 {
+		now := time.Now().UnixNano()
+		if now > item.expires {
+			c.removeExpired(hashed, item)
+			return nil, false
+		}
+		if item.refresh > 0 && now > item.refresh {
+			c.refresh(key)
+		}
+		if c.early.Early(now, item.expires, item.delta) {
+			return nil, false
+		}
 	}
 
+	if node != nil {
+		sh.recordRead(node)
+	}
+	if tracked {
+		sh.notify(policy.EvictionPolicy.OnAccess, key)
+	}
 	return item.value, true
 }
 
@@ -146,29 +264,372 @@ func (c *Cache) Delete(key string) {
 	sh := c.getShard(hashed)
 
 	sh.mu.Lock()
-	delete(sh.items, hashed)
-	sh.mu.Unlock()
+	sh.remove(hashed, key, policy.Deleted)
+	sh.unlock()
+}
+
+// removeExpired removes item, which Get found expired, unless a Set replaced
+// it in the meantime.
+func (c *Cache) removeExpired(h uint32, item *Item) {
+	sh := c.getShard(h)
+	sh.mu.Lock()
+	if sh.lookup(h, item.key) == item {
+		sh.remove(h, item.key, policy.Expired)
+	}
+	sh.unlock()
+}
+
+// SetMaxEntries bounds the cache to about n items, split evenly across shards.
+// A value of n <= 0 removes the bound. When a shard is full, Set evicts an
+// arbitrary item from it to make room for a new key.
+// It replaces any bound set by SetTinyLFU or SetEvictionPolicy.
+func (c *Cache) SetMaxEntries(n int) {
+	per := 0
+	if n > 0 {
+		per = (n + numShards - 1) / numShards
+	}
+	for _, sh := range c.shards {
+		sh.mu.Lock()
+		sh.maxEntries = per
+		sh.lfu = nil
+		sh.setPolicy(nil)
+		for per > 0 && sh.count > per {
+			sh.evict()
+		}
+		sh.unlock()
+	}
+}
+
+// SetTinyLFU bounds the cache to about n items, split evenly across shards,
+// and chooses which items to keep with W-TinyLFU: a new key only displaces an
+// older one if it has been accessed more often recently. This keeps popular
+// items under skewed workloads far better than SetMaxEntries does.
+// A value of n <= 0 removes the bound. It replaces any bound set by
+// SetMaxEntries or SetEvictionPolicy; items already stored are admitted in
+// arbitrary order.
+//
+// Gets record their hits in a small per-shard buffer, applied in batches,
+// so that they keep taking only a read lock. Hits are dropped when the
+// buffer is full and the shard is busy, as in ristretto.
+func (c *Cache) SetTinyLFU(n int) {
+	per := 0
+	if n > 0 {
+		per = (n + numShards - 1) / numShards
+	}
+	for _, sh := range c.shards {
+		sh.mu.Lock()
+		sh.maxEntries = 0
+		sh.lfu = nil
+		sh.setPolicy(nil)
+		sh.readMu.Lock()
+		clear(sh.reads[:sh.nReads])
+		sh.nReads = 0
+		sh.readMu.Unlock()
+		if per > 0 {
+			sh.lfu = newTinyLFU(per)
+			items := make([]*Item, 0, sh.count)
+			for _, head := range sh.items {
+				for item := head; item != nil; item = item.next {
+					items = append(items, item)
+				}
+			}
+			for _, item := range items {
+				sh.admit(item)
+			}
+		}
+		sh.unlock()
+	}
+}
+
+// admit records a Set of item with the TinyLFU policy, and removes the item
+// the policy evicts, if any, to keep the shard within its capacity.
+// The caller must hold sh.mu for writing.
+func (sh *shard) admit(item *Item) {
+	sh.drainReads()
+	if n := item.node; n != nil && n.owner == sh.lfu && n.seg != detached {
+		sh.lfu.access(n)
+		return
+	}
+	item.node = &lfuNode{key: item.key, hash: item.hash, owner: sh.lfu}
+	if victim := sh.lfu.add(item.node); victim != nil {
+		sh.remove(victim.hash, victim.key, policy.Capacity)
+	}
+}
+
+// recordRead buffers a Get hit on node. When the buffer fills up, it is
+// applied to the policy if the shard's lock is free; otherwise later hits
+// are dropped until a Set drains it.
+func (sh *shard) recordRead(node *lfuNode) {
+	sh.readMu.Lock()
+	if sh.nReads < readBufSize {
+		sh.reads[sh.nReads] = node
+		sh.nReads++
+	}
+	full := sh.nReads == readBufSize
+	sh.readMu.Unlock()
+
+	if full && sh.mu.TryLock() {
+		sh.drainReads()
+		sh.mu.Unlock()
+	}
+}
+
+// drainReads applies the buffered hits to the policy once the buffer is full.
+// The caller must hold sh.mu for writing.
+func (sh *shard) drainReads() {
+	sh.readMu.Lock()
+	defer sh.readMu.Unlock()
+	if sh.nReads < readBufSize {
+		return
+	}
+	for i, node := range sh.reads {
+		if sh.lfu != nil {
+			sh.lfu.access(node)
+		}
+		sh.reads[i] = nil
+	}
+	sh.nReads = 0
+}
+
+// evict removes one item from the shard: the victim of its TinyLFU or
+// eviction policy if it has one, otherwise one chosen by Go's randomized map
+// iteration.
+// The caller must hold sh.mu.
+func (sh *shard) evict() {
+	if sh.lfu != nil {
+		if victim := sh.lfu.victim(); victim != nil {
+			sh.remove(victim.hash, victim.key, policy.Capacity)
+			return
+		}
+	}
+	if sh.policy != nil {
+		if key, ok := sh.victim(); ok {
+			if h := sh.hash(key); sh.lookup(h, key) != nil {
+				sh.remove(h, key, policy.Capacity)
+				return
+			}
+		}
+	}
+	for h, item := range sh.items {
+		sh.remove(h, item.key, policy.Capacity)
+		return
+	}
+}
+
+// lookup returns the item stored under key, whose hash is h, or nil.
+// The caller must hold sh.mu.
+func (sh *shard) lookup(h uint32, key string) *Item {
+	for item := sh.items[h]; item != nil; item = item.next {
+		if item.key == key {
+			return item
+		}
+	}
+	return nil
+}
+
+// store links item into the chain for h and into the expiry index,
+// replacing the item with the same key if there is one. Items are never
+// modified once linked, except for their links, so Get can read one after
+// releasing the lock.
+// The caller must hold sh.mu for writing.
+func (sh *shard) store(h uint32, item *Item) {
+	sh.index(item)
+	var prev *Item
+	for cur := sh.items[h]; cur != nil; prev, cur = cur, cur.next {
+		if cur.key == item.key {
+			sh.unindex(cur)
+			sh.cost += item.cost - cur.cost
+			item.node = cur.node
+			item.next = cur.next
+			sh.link(h, prev, item)
+			if sh.policy != nil {
+				sh.notify(policy.EvictionPolicy.OnAccess, item.key)
+			}
+			sh.evictions.Add(cur.key, cur.value, policy.Replaced)
+			return
+		}
+	}
+	item.next = sh.items[h]
+	sh.items[h] = item
+	sh.cost += item.cost
+	sh.count++
+	if sh.count > sh.peak {
+		sh.peak = sh.count
+	}
+	if sh.policy != nil {
+		sh.notify(policy.EvictionPolicy.OnInsert, item.key)
+	}
+}
+
+// remove unlinks the item stored under key, if any, and records it for the
+// OnEvict callback with reason.
+// The caller must hold sh.mu for writing.
+func (sh *shard) remove(h uint32, key string, reason policy.EvictReason) {
+	var prev *Item
+	for cur := sh.items[h]; cur != nil; prev, cur = cur, cur.next {
+		if cur.key == key {
+			sh.unlink(h, prev, cur)
+			sh.evictions.Add(cur.key, cur.value, reason)
+			return
+		}
+	}
+}
+
+// link makes item follow prev in the chain for h, or head it if prev is nil.
+func (sh *shard) link(h uint32, prev, item *Item) {
+	if prev == nil {
+		sh.items[h] = item
+	} else {
+		prev.next = item
+	}
+}
+
+// unlink removes item, which follows prev, from the chain for h and from
+// the expiry index.
+func (sh *shard) unlink(h uint32, prev, item *Item) {
+	if prev == nil && item.next == nil {
+		delete(sh.items, h)
+	} else {
+		sh.link(h, prev, item.next)
+	}
+	sh.unindex(item)
+	if sh.lfu != nil && item.node != nil {
+		sh.lfu.remove(item.node)
+	}
+	if sh.policy != nil {
+		sh.notify(policy.EvictionPolicy.OnDelete, item.key)
+	}
+	sh.cost -= item.cost
+	sh.count--
+}
+
+// indexed reports whether item belongs in the expiry index.
+func (sh *shard) indexed(item *Item) bool {
+	return sh.tick > 0 && item.expires > 0
+}
+
+// bucket returns the tick of the expiry bucket holding item. If the wall
+// clock stepped back, that tick may already have been swept; the item then
+// goes in the next bucket to be swept.
+func (sh *shard) bucket(item *Item) int64 {
+	return max(item.expires/sh.tick, sh.swept)
+}
+
+// index pushes item onto its expiry bucket.
+func (sh *shard) index(item *Item) {
+	if !sh.indexed(item) {
+		return
+	}
+	t := sh.bucket(item)
+	if head := sh.buckets[t]; head != nil {
+		head.expPrev = item
+		item.expNext = head
+	}
+	sh.buckets[t] = item
+}
+
+// unindex removes item from its expiry bucket.
+func (sh *shard) unindex(item *Item) {
+	if !sh.indexed(item) {
+		return
+	}
+	if item.expPrev != nil {
+		item.expPrev.expNext = item.expNext
+	} else if t := sh.bucket(item); item.expNext != nil {
+		sh.buckets[t] = item.expNext
+	} else {
+		delete(sh.buckets, t)
+	}
+	if item.expNext != nil {
+		item.expNext.expPrev = item.expPrev
+	}
+	item.expPrev, item.expNext = nil, nil
+}
+
+// expire removes the items in buckets whose tick has fully elapsed by now,
+// stopping after limit items. It reports whether every such bucket is empty.
+// The caller must hold sh.mu for writing.
+func (sh *shard) expire(now int64, limit int) bool {
+	for end := now / sh.tick; sh.swept < end; sh.swept++ {
+		for item := sh.buckets[sh.swept]; item != nil; item = sh.buckets[sh.swept] {
+			if limit == 0 {
+				return false
+			}
+			sh.remove(item.hash, item.key, policy.Expired)
+			limit--
+		}
+	}
+	return true
+}
+
+// shrink rebuilds the shard's maps once they hold under a quarter of their
+// peak item count. Go maps never release their buckets, so without it the
+// memory of a mass expiry would stay allocated.
+// The caller must hold sh.mu for writing.
+func (sh *shard) shrink() {
+	if sh.peak < shrinkMin || sh.count >= sh.peak/4 {
+		return
+	}
+	items := make(map[uint32]*Item, sh.count)
+	for h, item := range sh.items {
+		items[h] = item
+	}
+	buckets := make(map[int64]*Item, len(sh.buckets))
+	for t, item := range sh.buckets {
+		buckets[t] = item
+	}
+	sh.items, sh.buckets, sh.peak = items, buckets, sh.count
 }
 
 // cleanup runs periodically to remove expired items from the cache.//
-// This function runs as a background goroutine and checks for expired items
-// at intervals of `ttl / 2`, ensuring efficient memory management.
-func (c *Cache) cleanup() {
+// This function runs as a background goroutine and, at intervals of `ttl / 2`,
+// empties the expiry buckets whose time has passed. Every item with a TTL is
+// in a bucket, so all of them are removed within two intervals of expiring,
+// whether or not they are read again, unless more than sweepLimit items of a
+// shard expire at once; the excess is removed over the following intervals.
+func (c *Cache) cleanup(ctx context.Context) {
+	defer close(c.exited)
 	tick := time.NewTicker(c.ttl / 2)
 	defer tick.Stop()
 
-	for range tick.C {
+	for {
+		select {
+		case <-tick.C:
+		case <-ctx.Done():
+			return
+		}
 		now := time.Now().UnixNano()
 		for _, sh := range c.shards {
-			sh.mu.Lock()
-			for i := 0; i < ringSize; i++ {
-				node := &sh.ringBuf[i]
-				if node.expires > 0 && now > node.expires {
-					delete(sh.items, node.key)
-					node.expires = 0
-				}
-			}
-			sh.mu.Unlock()
+			sh.sweep(now)
 		}
 	}
 }
+
+// sweep removes up to sweepLimit items of the shard that expired by now,
+// sweepBatch per lock acquisition, and leaves the rest to the next tick.
+// It rebuilds the shard's maps once no expired bucket is left.
+func (sh *shard) sweep(now int64) {
+	for limit := sweepLimit; limit > 0; limit -= sweepBatch {
+		sh.mu.Lock()
+		done := sh.expire(now, min(limit, sweepBatch))
+		if done {
+			sh.shrink()
+		}
+		sh.unlock()
+		if done {
+			return
+		}
+	}
+}
+
+// Len returns the number of items stored in the cache.
+// Expired items that have not yet been removed by cleanup or by a Get are counted.
+func (c *Cache) Len() int {
+	n := 0
+	for _, sh := range c.shards {
+		sh.mu.RLock()
+		n += sh.count
+		sh.mu.RUnlock()
+	}
+	return n
+}
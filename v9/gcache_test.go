@@ -1,9 +1,15 @@
 package v9
 
 import (
+	"context"
+	"io"
+	"strconv"
 	"sync"
 	"testing"
 	"time"
+
+	"benchmark-gocache/conformance"
+	"benchmark-gocache/gcstats"
 )
 
 // TestCache_SetAndGet verifies that values are correctly
@@ -160,3 +166,166 @@ func TestNew_WithCustomTTL(t *testing.T) {
 		t.Errorf("Expected TTL to be %v, got %v", ttl, c.ttl)
 	}
 }
+
+func TestCache_MaxEntries(t *testing.T) {
+	cache := New(10 * time.Minute)
+	cache.SetMaxEntries(100)
+
+	for i := 0; i < 1000; i++ {
+		cache.Set(strconv.Itoa(i), i, DefaultExpiration)
+	}
+	if n := cache.Len(); n > 100+numShards {
+		t.Errorf("Len() = %d, want at most %d", n, 100+numShards)
+	}
+	if _, found := cache.Get("999"); !found {
+		t.Errorf("Expected the last key set to be present")
+	}
+
+	cache.SetMaxEntries(10)
+	if n := cache.Len(); n > 10+numShards {
+		t.Errorf("Len() after shrinking = %d, want at most %d", n, 10+numShards)
+	}
+
+	cache.SetMaxEntries(0)
+	for i := 0; i < 1000; i++ {
+		cache.Set(strconv.Itoa(i), i, DefaultExpiration)
+	}
+	if n := cache.Len(); n != 1000 {
+		t.Errorf("Len() after removing the bound = %d, want 1000", n)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	conformance.Run(t, func(ttl time.Duration) conformance.Cache {
+		return New(ttl)
+	})
+}
+
+func TestEarlyExpiration(t *testing.T) {
+	conformance.RunEarly(t, func(ttl time.Duration) conformance.EarlyCache {
+		return New(ttl)
+	})
+}
+
+func TestEvictCallbacks(t *testing.T) {
+	conformance.RunEvict(t, func(ttl time.Duration) conformance.EvictCache {
+		return New(ttl)
+	})
+}
+
+// TestCache_HashCollision verifies that keys whose hashes collide are kept
+// apart, including by the cleanup goroutine.
+func TestCache_HashCollision(t *testing.T) {
+	pairs := [][2]string{
+		{"key-375908", "key-1294886"},
+		{"key-375909", "key-1294887"},
+		{"a", "\x00a"},
+	}
+	for _, p := range pairs {
+		cache := New(time.Minute)
+		if cache.hashKey(p[0]) != cache.hashKey(p[1]) {
+			t.Fatalf("%q and %q do not collide", p[0], p[1])
+		}
+
+		cache.Set(p[0], "first", DefaultExpiration)
+		cache.Set(p[1], "second", DefaultExpiration)
+		if n := cache.Len(); n != 2 {
+			t.Errorf("Len() = %d, want 2", n)
+		}
+		if val, found := cache.Get(p[0]); !found || val != "first" {
+			t.Errorf("Get(%q) = %v, %v; want first, true", p[0], val, found)
+		}
+		if val, found := cache.Get(p[1]); !found || val != "second" {
+			t.Errorf("Get(%q) = %v, %v; want second, true", p[1], val, found)
+		}
+
+		cache.Set(p[0], "updated", DefaultExpiration)
+		cache.Delete(p[1])
+		if _, found := cache.Get(p[1]); found {
+			t.Errorf("Expected %q to be deleted", p[1])
+		}
+		if val, found := cache.Get(p[0]); !found || val != "updated" {
+			t.Errorf("Get(%q) = %v, %v; want updated, true", p[0], val, found)
+		}
+		if n := cache.Len(); n != 1 {
+			t.Errorf("Len() after Delete = %d, want 1", n)
+		}
+	}
+
+	// The janitor must only remove the expired key of a colliding pair.
+	cache := New(100 * time.Millisecond)
+	cache.Set(pairs[0][0], "short", 50*time.Millisecond)
+	cache.Set(pairs[0][1], "forever", NoExpiration)
+	time.Sleep(300 * time.Millisecond)
+	if n := cache.Len(); n != 1 {
+		t.Errorf("Len() after cleanup = %d, want 1", n)
+	}
+	if val, found := cache.Get(pairs[0][1]); !found || val != "forever" {
+		t.Errorf("Get(%q) = %v, %v; want forever, true", pairs[0][1], val, found)
+	}
+}
+
+// TestCache_ExpirySoak verifies that cleanup removes every expired item, even
+// when far more are set than are ever read, and that the shards then give
+// their memory back.
+func TestCache_ExpirySoak(t *testing.T) {
+	const n = 200_000 // Over 4096 items per shard
+	cache := New(500 * time.Millisecond)
+	base := gcstats.LiveHeap()
+
+	for i := 0; i < n; i++ {
+		cache.Set(strconv.Itoa(i), i, DefaultExpiration)
+	}
+	if full := gcstats.LiveHeap(); full < base+n*16 {
+		t.Fatalf("live heap grew by %d bytes for %d items, too little to measure", full-base, n)
+	}
+
+	for deadline := time.Now().Add(3 * time.Second); cache.Len() > 0; {
+		if time.Now().After(deadline) {
+			t.Fatalf("Len() = %d after mass expiry, want 0", cache.Len())
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	for i, sh := range cache.shards {
+		sh.mu.RLock()
+		if len(sh.buckets) != 0 {
+			t.Errorf("shard %d has %d expiry buckets left", i, len(sh.buckets))
+		}
+		sh.mu.RUnlock()
+	}
+	if live := gcstats.LiveHeap(); live > base+256<<10 {
+		t.Errorf("live heap is %d bytes above baseline after mass expiry", live-base)
+	}
+}
+
+// TestLifecycle verifies that Close and context cancellation stop the cleanup goroutine.
+func TestCache_SweepLimit(t *testing.T) {
+	const n = 3 * sweepLimit * numShards
+	cache := New(time.Hour)
+	defer cache.Close()
+	for i := 0; i < n; i++ {
+		cache.Set(strconv.Itoa(i), i, time.Millisecond)
+	}
+
+	// Sweeping far past the expiries removes at most sweepLimit items per
+	// shard and tick, until every shard is empty.
+	now := time.Now().Add(2 * time.Hour).UnixNano()
+	for tick := 1; cache.Len() > 0; tick++ {
+		if tick > 10 {
+			t.Fatalf("Len() = %d after %d ticks, want 0", cache.Len(), tick-1)
+		}
+		for i, sh := range cache.shards {
+			before := sh.count
+			sh.sweep(now)
+			if want := max(before-sweepLimit, 0); sh.count != want {
+				t.Fatalf("tick %d: shard %d went from %d to %d items, want %d", tick, i, before, sh.count, want)
+			}
+		}
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	conformance.RunLifecycle(t, func(ctx context.Context, ttl time.Duration) io.Closer {
+		return NewWithContext(ctx, ttl)
+	})
+}
@@ -0,0 +1,50 @@
+package v9
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCache_GetOrLoad(t *testing.T) {
+	cache := New(10 * time.Minute)
+	defer cache.Close()
+
+	var calls atomic.Int32
+	load := func(ctx context.Context) (any, time.Duration, error) {
+		calls.Add(1)
+		time.Sleep(20 * time.Millisecond)
+		return "loaded", NoExpiration, nil
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err := cache.GetOrLoad(context.Background(), "key", load); err != nil || v != "loaded" {
+				t.Errorf("GetOrLoad() = %v, %v; want loaded, nil", v, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := calls.Load(); n != 1 {
+		t.Errorf("loader called %d times, want 1", n)
+	}
+	if v, found := cache.Get("key"); !found || v != "loaded" {
+		t.Errorf("Get() after GetOrLoad = %v, %v; want loaded, true", v, found)
+	}
+
+	errBackend := errors.New("backend down")
+	_, err := cache.GetOrLoad(context.Background(), "missing", func(ctx context.Context) (any, time.Duration, error) {
+		return nil, 0, errBackend
+	})
+	if err != errBackend {
+		t.Errorf("GetOrLoad() error = %v, want %v", err, errBackend)
+	}
+	if _, found := cache.Get("missing"); found {
+		t.Errorf("Expected a failed load to store nothing")
+	}
+}
@@ -0,0 +1,76 @@
+package v9
+
+import (
+	"context"
+	"time"
+)
+
+// RefreshFunc reloads the value of key, and returns the ttl to store it with,
+// as passed to Set. SetRefresh registers one with the cache.
+type RefreshFunc func(ctx context.Context, key string) (value interface{}, ttl time.Duration, err error)
+
+// refresher is the configuration set by SetRefresh.
+type refresher struct {
+	load  RefreshFunc
+	stale int64         // Nanoseconds an item is served past its ttl
+	ahead float64       // Fraction of the ttl before its end from which hits reload
+	slots chan struct{} // Holds a token per refresh in flight
+}
+
+// SetRefresh makes Gets reload items in the background with load instead of
+// letting popular keys expire and block every caller on a reload:
+//
+//   - stale-while-revalidate: an item stays readable for stale past its ttl,
+//     the soft TTL. Reading it then returns the stale value and triggers a
+//     reload. It is removed once ttl+stale, the hard TTL, has passed.
+//   - refresh-ahead: with 0 < ahead < 1, reading an item within the last
+//     ahead fraction of its ttl also triggers a reload, so that popular
+//     items are replaced before they go stale at all.
+//
+// Each key has at most one reload in flight, shared with GetOrLoad, and at
+// most maxConcurrent reloads run at once (1 if maxConcurrent <= 0). A Get
+// that finds them all busy skips the reload; a later Get triggers it. A
+// failed reload leaves the item as it was, unless load returned a ttl > 0
+// with its error, which then suspends the reloads of the key for that ttl.
+//
+// It only applies to items stored after the call. A nil load turns both
+// modes off.
+func (c *Cache) SetRefresh(load RefreshFunc, stale time.Duration, ahead float64, maxConcurrent int) {
+	if load == nil {
+		c.refresher.Store(nil)
+		return
+	}
+	c.refresher.Store(&refresher{
+		load:  load,
+		stale: int64(max(stale, 0)),
+		ahead: min(max(ahead, 0), 1),
+		slots: make(chan struct{}, max(maxConcurrent, 1)),
+	})
+}
+
+// deadlines returns the hard expiry and the reload time of an item whose
+// soft expiry, ttl from now, is soft.
+func (r *refresher) deadlines(soft int64, ttl time.Duration) (expires, refresh int64) {
+	return soft + r.stale, soft - int64(r.ahead*float64(ttl))
+}
+
+// refresh reloads key in the background, unless the cache has no refresher
+// or no free slot.
+func (c *Cache) refresh(key string) {
+	r := c.refresher.Load()
+	if r == nil {
+		return
+	}
+	select {
+	case r.slots <- struct{}{}:
+	default:
+		return
+	}
+	load := func(ctx context.Context) (interface{}, time.Duration, error) {
+		defer func() { <-r.slots }()
+		return r.load(ctx, key)
+	}
+	if !c.loads.Refresh(c, key, load) {
+		<-r.slots
+	}
+}
@@ -0,0 +1,139 @@
+package v9
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitFor polls cond until it holds, failing t after a second.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); !cond(); {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// TestCache_StaleWhileRevalidate verifies that a stale item is served while
+// one reload replaces it, and is removed at its hard TTL.
+func TestCache_StaleWhileRevalidate(t *testing.T) {
+	cache := New(time.Minute)
+	defer cache.Close()
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	cache.SetRefresh(func(ctx context.Context, key string) (interface{}, time.Duration, error) {
+		calls.Add(1)
+		<-release
+		return "fresh", 50 * time.Millisecond, nil
+	}, 200*time.Millisecond, 0, 4)
+
+	cache.Set("key", "stale", 50*time.Millisecond)
+	if val, found := cache.Get("key"); !found || val != "stale" {
+		t.Fatalf("Get() before the soft TTL = %v, %v; want stale, true", val, found)
+	}
+	if n := calls.Load(); n != 0 {
+		t.Fatalf("loader called %d times before the soft TTL, want 0", n)
+	}
+	time.Sleep(70 * time.Millisecond)
+	for i := 0; i < 10; i++ {
+		if val, found := cache.Get("key"); !found || val != "stale" {
+			t.Fatalf("Get() past the soft TTL = %v, %v; want stale, true", val, found)
+		}
+	}
+	close(release)
+	waitFor(t, "the reload", func() bool {
+		val, _ := cache.Get("key")
+		return val == "fresh"
+	})
+	if n := calls.Load(); n != 1 {
+		t.Errorf("loader called %d times, want 1", n)
+	}
+
+	// A key whose reloads fail is removed at its hard TTL.
+	cache.SetRefresh(func(ctx context.Context, key string) (interface{}, time.Duration, error) {
+		return nil, 0, errors.New("backend down")
+	}, 100*time.Millisecond, 0, 1)
+	cache.Set("failing", "stale", 50*time.Millisecond)
+	time.Sleep(70 * time.Millisecond)
+	if val, found := cache.Get("failing"); !found || val != "stale" {
+		t.Fatalf("Get() past the soft TTL = %v, %v; want stale, true", val, found)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if _, found := cache.Get("failing"); found {
+		t.Errorf("Expected the item to be removed at its hard TTL")
+	}
+}
+
+// TestCache_RefreshAhead verifies that reading an item near the end of its
+// ttl reloads it before it goes stale.
+func TestCache_RefreshAhead(t *testing.T) {
+	cache := New(time.Minute)
+	defer cache.Close()
+
+	cache.SetRefresh(func(ctx context.Context, key string) (interface{}, time.Duration, error) {
+		return "fresh", time.Minute, nil
+	}, 0, 0.5, 1)
+
+	cache.Set("key", "old", 200*time.Millisecond)
+	cache.Get("key")
+	time.Sleep(20 * time.Millisecond)
+	if val, _ := cache.Get("key"); val != "old" {
+		t.Fatalf("Get() early in the ttl = %v, want old", val)
+	}
+	time.Sleep(110 * time.Millisecond)
+	cache.Get("key")
+	waitFor(t, "the reload", func() bool {
+		val, _ := cache.Get("key")
+		return val == "fresh"
+	})
+}
+
+// TestCache_RefreshConcurrency verifies that reloads never exceed the bound.
+func TestCache_RefreshConcurrency(t *testing.T) {
+	cache := New(time.Minute)
+	defer cache.Close()
+
+	var running, peak, calls atomic.Int32
+	release := make(chan struct{})
+	cache.SetRefresh(func(ctx context.Context, key string) (interface{}, time.Duration, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		calls.Add(1)
+		<-release
+		return "fresh", time.Minute, nil
+	}, time.Minute, 0, 2)
+
+	for i := 0; i < 20; i++ {
+		cache.Set(strconv.Itoa(i), "stale", time.Millisecond)
+	}
+	time.Sleep(5 * time.Millisecond)
+	for i := 0; i < 20; i++ {
+		cache.Get(strconv.Itoa(i))
+	}
+	waitFor(t, "two reloads", func() bool { return calls.Load() == 2 })
+	close(release)
+	waitFor(t, "the reloads to finish", func() bool { return running.Load() == 0 })
+	if p := peak.Load(); p != 2 {
+		t.Errorf("peak concurrent reloads = %d, want 2", p)
+	}
+
+	// The Gets that found no free slot reload on a later Get.
+	waitFor(t, "every reload", func() bool {
+		fresh := 0
+		for i := 0; i < 20; i++ {
+			if val, _ := cache.Get(strconv.Itoa(i)); val == "fresh" {
+				fresh++
+			}
+		}
+		return fresh == 20
+	})
+}
@@ -0,0 +1,354 @@
+package v9
+
+// This file implements W-TinyLFU, the admission and eviction policy used by
+// Caffeine and ristretto, for the bounded mode enabled by SetTinyLFU.
+//
+// Each shard splits its capacity between a small LRU window, which absorbs
+// bursts of new keys, and a segmented LRU main area made of a probation and a
+// protected segment. A key evicted from the window is only admitted to the
+// main area if it has been seen more often than the probation segment's
+// least recently used key, which is evicted in its place. Access frequencies
+// are estimated by a count-min sketch fronted by a doorkeeper bloom filter,
+// so that keys seen once never reach the sketch, and are halved periodically
+// so that the policy adapts when popularity shifts.
+
+const (
+	// windowPercent is the share of a shard's capacity given to the window.
+	windowPercent = 1
+
+	// protectedPercent is the share of the main area given to the protected segment.
+	protectedPercent = 80
+
+	// sampleFactor sets how many recorded accesses, per unit of capacity,
+	// trigger a halving of all frequencies.
+	sampleFactor = 10
+
+	// readBufSize is the number of Get hits a shard buffers before they are
+	// applied to its policy.
+	readBufSize = 64
+)
+
+// segment identifies the list an lfuNode belongs to.
+type segment uint8
+
+const (
+	detached segment = iota // Not in any list: removed, or not yet added
+	window
+	probation
+	protected
+)
+
+// lfuNode tracks one key in a shard's W-TinyLFU policy. Items stored under
+// the same key share their node.
+type lfuNode struct {
+	key        string   // Key of the item
+	hash       uint32   // Hash of key, used for the shard map and the sketch
+	owner      *tinyLFU // Policy the node was created by
+	seg        segment  // List the node is in
+	prev, next *lfuNode // Neighbours in that list
+}
+
+// lfuList is an intrusive, circular doubly linked list of nodes, from most
+// to least recently used.
+type lfuList struct {
+	root lfuNode // Sentinel: root.next is the front, root.prev the back
+	len  int     // Number of nodes in the list
+}
+
+// init empties the list.
+func (l *lfuList) init() {
+	l.root.next = &l.root
+	l.root.prev = &l.root
+	l.len = 0
+}
+
+// pushFront inserts n, which must be detached, at the front of the list.
+func (l *lfuList) pushFront(n *lfuNode, seg segment) {
+	n.prev = &l.root
+	n.next = l.root.next
+	n.prev.next = n
+	n.next.prev = n
+	n.seg = seg
+	l.len++
+}
+
+// remove detaches n, which must be in the list.
+func (l *lfuList) remove(n *lfuNode) {
+	n.prev.next = n.next
+	n.next.prev = n.prev
+	n.prev, n.next = nil, nil
+	n.seg = detached
+	l.len--
+}
+
+// back returns the least recently used node, or nil if the list is empty.
+func (l *lfuList) back() *lfuNode {
+	if l.len == 0 {
+		return nil
+	}
+	return l.root.prev
+}
+
+// tinyLFU is the W-TinyLFU policy of one shard. It only orders keys and
+// picks victims; the shard removes them. It is guarded by the shard's mutex.
+type tinyLFU struct {
+	sketch *cmSketch   // Frequency estimates of keys seen more than once
+	door   *doorkeeper // Keys seen at least once since the last reset
+
+	samples  int // Accesses recorded since the last reset
+	resetAt  int // Value of samples that triggers a reset
+	capacity int // Maximum number of tracked keys
+
+	windowCap    int // Maximum length of the window
+	protectedCap int // Maximum length of the protected segment
+
+	window, probation, protected lfuList
+}
+
+// newTinyLFU returns a policy that tracks at most capacity keys.
+func newTinyLFU(capacity int) *tinyLFU {
+	windowCap := max(1, capacity*windowPercent/100)
+	p := &tinyLFU{
+		sketch:       newCMSketch(capacity),
+		door:         newDoorkeeper(capacity),
+		resetAt:      sampleFactor * capacity,
+		capacity:     capacity,
+		windowCap:    windowCap,
+		protectedCap: (capacity - windowCap) * protectedPercent / 100,
+	}
+	p.window.init()
+	p.probation.init()
+	p.protected.init()
+	return p
+}
+
+// len returns the number of keys the policy tracks.
+func (p *tinyLFU) len() int {
+	return p.window.len + p.probation.len + p.protected.len
+}
+
+// list returns the list holding nodes of segment seg.
+func (p *tinyLFU) list(seg segment) *lfuList {
+	switch seg {
+	case window:
+		return &p.window
+	case probation:
+		return &p.probation
+	default:
+		return &p.protected
+	}
+}
+
+// record counts an access to the key whose hash is h.
+func (p *tinyLFU) record(h uint32) {
+	x := spread(h)
+	if p.door.add(x) {
+		p.sketch.increment(x)
+	}
+	if p.samples++; p.samples >= p.resetAt {
+		p.sketch.halve()
+		p.door.clear()
+		p.samples = 0
+	}
+}
+
+// frequency estimates how often the key whose hash is h has been accessed.
+func (p *tinyLFU) frequency(h uint32) int {
+	x := spread(h)
+	f := p.sketch.estimate(x)
+	if p.door.contains(x) {
+		f++
+	}
+	return f
+}
+
+// add starts tracking n, a new key, and returns the node to evict to stay
+// within capacity, or nil. The victim may be n itself.
+func (p *tinyLFU) add(n *lfuNode) *lfuNode {
+	p.record(n.hash)
+	p.window.pushFront(n, window)
+	if p.window.len <= p.windowCap {
+		return nil
+	}
+
+	// The window overflowed: its oldest key becomes a candidate for the
+	// main area, which takes it outright while there is room.
+	candidate := p.window.back()
+	p.window.remove(candidate)
+	if p.len() < p.capacity {
+		p.probation.pushFront(candidate, probation)
+		return nil
+	}
+	victim := p.probation.back()
+	if victim == nil {
+		victim = p.protected.back()
+	}
+	if victim == nil || p.frequency(candidate.hash) <= p.frequency(victim.hash) {
+		return candidate
+	}
+	p.remove(victim)
+	p.probation.pushFront(candidate, probation)
+	return victim
+}
+
+// victim returns the node the policy would evict first to make room: the
+// least recently used node of probation, then of protected, then of the
+// window. It returns nil if the policy tracks no node.
+func (p *tinyLFU) victim() *lfuNode {
+	for _, l := range []*lfuList{&p.probation, &p.protected, &p.window} {
+		if n := l.back(); n != nil {
+			return n
+		}
+	}
+	return nil
+}
+
+// access records a hit on n and updates its recency. A hit in probation
+// promotes n to the protected segment, whose least recently used node is
+// demoted back to probation if it is full.
+func (p *tinyLFU) access(n *lfuNode) {
+	if n.owner != p || n.seg == detached {
+		return
+	}
+	p.record(n.hash)
+	switch n.seg {
+	case window, protected:
+		seg, l := n.seg, p.list(n.seg)
+		l.remove(n)
+		l.pushFront(n, seg)
+	case probation:
+		p.probation.remove(n)
+		p.protected.pushFront(n, protected)
+		if p.protected.len > p.protectedCap {
+			demoted := p.protected.back()
+			p.protected.remove(demoted)
+			p.probation.pushFront(demoted, probation)
+		}
+	}
+}
+
+// remove stops tracking n. It is a no-op if n is already detached.
+func (p *tinyLFU) remove(n *lfuNode) {
+	if n.owner != p || n.seg == detached {
+		return
+	}
+	p.list(n.seg).remove(n)
+}
+
+// spread turns a 32-bit key hash into a well-mixed 64-bit value, from which
+// the sketch and the doorkeeper derive their indexes by double hashing.
+func spread(h uint32) uint64 {
+	x := uint64(h) * 0x9e3779b97f4a7c15
+	x ^= x >> 29
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 32
+	return x
+}
+
+// probe returns the i-th index derived from x, masked by mask.
+func probe(x uint64, i int, mask uint64) uint64 {
+	return (x + uint64(i)*(x>>32|1)) & mask
+}
+
+// cmDepth is the number of rows of the count-min sketch.
+const cmDepth = 4
+
+// cmSketch is a count-min sketch of 4-bit counters, sixteen to a word.
+// Counters saturate at 15, which is enough to rank keys for admission.
+type cmSketch struct {
+	rows [cmDepth][]uint64 // Packed counters
+	mask uint64            // Counters per row, minus one
+}
+
+// newCMSketch returns a sketch sized for about n distinct keys.
+func newCMSketch(n int) *cmSketch {
+	width := nextPow2(max(n, 16))
+	s := &cmSketch{mask: uint64(width - 1)}
+	for i := range s.rows {
+		s.rows[i] = make([]uint64, width/16)
+	}
+	return s
+}
+
+// increment adds one to the counters of x that are not yet saturated.
+func (s *cmSketch) increment(x uint64) {
+	for i := range s.rows {
+		idx := probe(x, i, s.mask)
+		word, shift := idx/16, (idx%16)*4
+		if (s.rows[i][word]>>shift)&0xf < 15 {
+			s.rows[i][word] += 1 << shift
+		}
+	}
+}
+
+// estimate returns the smallest counter of x, an upper bound of its count.
+func (s *cmSketch) estimate(x uint64) int {
+	least := 15
+	for i := range s.rows {
+		idx := probe(x, i, s.mask)
+		least = min(least, int((s.rows[i][idx/16]>>((idx%16)*4))&0xf))
+	}
+	return least
+}
+
+// halve divides every counter by two.
+func (s *cmSketch) halve() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] = (s.rows[i][j] >> 1) & 0x7777777777777777
+		}
+	}
+}
+
+// doorkeeperProbes is the number of bits a doorkeeper sets per key.
+const doorkeeperProbes = 3
+
+// doorkeeper is a bloom filter of the keys seen since the last reset.
+type doorkeeper struct {
+	bits []uint64 // Bit set
+	mask uint64   // Number of bits, minus one
+}
+
+// newDoorkeeper returns a filter sized for about n keys.
+func newDoorkeeper(n int) *doorkeeper {
+	size := nextPow2(max(8*n, 64))
+	return &doorkeeper{bits: make([]uint64, size/64), mask: uint64(size - 1)}
+}
+
+// add inserts x and reports whether it was already present.
+func (d *doorkeeper) add(x uint64) bool {
+	present := true
+	for i := 0; i < doorkeeperProbes; i++ {
+		idx := probe(x, i, d.mask)
+		if d.bits[idx/64]&(1<<(idx%64)) == 0 {
+			present = false
+			d.bits[idx/64] |= 1 << (idx % 64)
+		}
+	}
+	return present
+}
+
+// contains reports whether x may have been added.
+func (d *doorkeeper) contains(x uint64) bool {
+	for i := 0; i < doorkeeperProbes; i++ {
+		idx := probe(x, i, d.mask)
+		if d.bits[idx/64]&(1<<(idx%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// clear removes every key.
+func (d *doorkeeper) clear() {
+	clear(d.bits)
+}
+
+// nextPow2 returns the smallest power of two that is at least n.
+func nextPow2(n int) int {
+	p := 1
+	for p < n {
+		p <<= 1
+	}
+	return p
+}
@@ -0,0 +1,139 @@
+package v9
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestCMSketch verifies that the sketch never underestimates, saturates at
+// 15 and halves its counters.
+func TestCMSketch(t *testing.T) {
+	s := newCMSketch(1024)
+	for i := 0; i < 10; i++ {
+		s.increment(spread(1))
+	}
+	for i := 0; i < 100; i++ {
+		s.increment(spread(2))
+	}
+	if got := s.estimate(spread(1)); got < 10 {
+		t.Errorf("estimate after 10 increments = %d, want at least 10", got)
+	}
+	if got := s.estimate(spread(2)); got != 15 {
+		t.Errorf("estimate after 100 increments = %d, want 15", got)
+	}
+	if got := s.estimate(spread(3)); got > 1 {
+		t.Errorf("estimate of an unseen key = %d, want about 0", got)
+	}
+
+	s.halve()
+	if got := s.estimate(spread(2)); got != 7 {
+		t.Errorf("estimate after halving 15 = %d, want 7", got)
+	}
+}
+
+// TestDoorkeeper verifies that the doorkeeper reports keys it has seen and is
+// emptied by clear.
+func TestDoorkeeper(t *testing.T) {
+	d := newDoorkeeper(1000)
+	for h := uint32(0); h < 1000; h++ {
+		if d.add(spread(h)) && h < 10 {
+			t.Errorf("add(%d) reported a key never added before", h)
+		}
+	}
+	for h := uint32(0); h < 1000; h++ {
+		if !d.contains(spread(h)) {
+			t.Fatalf("contains(%d) = false after add", h)
+		}
+	}
+	d.clear()
+	if d.contains(spread(1)) {
+		t.Errorf("contains after clear = true")
+	}
+}
+
+// TestTinyLFU_Bounded verifies that the policy never tracks more keys than
+// its capacity and keeps a key that stays popular through a scan of new ones.
+func TestTinyLFU_Bounded(t *testing.T) {
+	p := newTinyLFU(100)
+	hot := &lfuNode{key: "hot", hash: 1, owner: p}
+	if victim := p.add(hot); victim != nil {
+		t.Fatalf("add into an empty policy evicted %q", victim.key)
+	}
+	for i := 0; i < 20; i++ {
+		p.access(hot)
+	}
+
+	for i := 0; i < 10_000; i++ {
+		if i%10 == 0 {
+			p.access(hot)
+		}
+		n := &lfuNode{key: strconv.Itoa(i), hash: uint32(i + 2), owner: p}
+		if victim := p.add(n); victim != nil {
+			if victim == hot {
+				t.Fatalf("scan key %d evicted the hot key", i)
+			}
+			p.remove(victim)
+		}
+		if got := p.len(); got > 100 {
+			t.Fatalf("len() = %d after %d adds, want at most 100", got, i+2)
+		}
+	}
+	if hot.seg == detached {
+		t.Errorf("hot key is no longer tracked")
+	}
+}
+
+// TestCache_TinyLFU verifies that a cache bounded by SetTinyLFU stays within
+// its bound and keeps the popular keys when many one-off keys are written.
+func TestCache_TinyLFU(t *testing.T) {
+	const (
+		capacity = 800
+		hotKeys  = 200
+	)
+	cache := New(10 * time.Minute)
+	defer cache.Close()
+	cache.SetTinyLFU(capacity)
+
+	for round := 0; round < 20; round++ {
+		for i := 0; i < hotKeys; i++ {
+			key := "hot-" + strconv.Itoa(i)
+			if _, found := cache.Get(key); !found {
+				cache.Set(key, i, DefaultExpiration)
+			}
+		}
+	}
+	for i := 0; i < 50_000; i++ {
+		cache.Set("scan-"+strconv.Itoa(i), i, DefaultExpiration)
+		if i%10 == 0 {
+			cache.Get("hot-" + strconv.Itoa(i%hotKeys))
+		}
+	}
+
+	if n := cache.Len(); n > capacity {
+		t.Errorf("Len() = %d, want at most %d", n, capacity)
+	}
+	kept := 0
+	for i := 0; i < hotKeys; i++ {
+		if _, found := cache.Get("hot-" + strconv.Itoa(i)); found {
+			kept++
+		}
+	}
+	if kept < hotKeys*9/10 {
+		t.Errorf("%d of %d hot keys survived the scan, want at least 90%%", kept, hotKeys)
+	}
+
+	// Switching back to SetMaxEntries drops the policy.
+	cache.SetMaxEntries(100)
+	cache.Set("after", 1, DefaultExpiration)
+	if n := cache.Len(); n > 100+numShards {
+		t.Errorf("Len() after SetMaxEntries = %d, want at most %d", n, 100+numShards)
+	}
+	cache.SetTinyLFU(0)
+	for i := 0; i < 1000; i++ {
+		cache.Set("unbounded-"+strconv.Itoa(i), i, DefaultExpiration)
+	}
+	if n := cache.Len(); n < 1000 {
+		t.Errorf("Len() after removing the bound = %d, want at least 1000", n)
+	}
+}
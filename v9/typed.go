@@ -0,0 +1,380 @@
+package v9
+
+import (
+	"context"
+	"hash/maphash"
+	"sync"
+	"time"
+)
+
+// Hasher hashes a key to pick its shard and map slot. Keys whose hashes
+// collide are told apart by ==, so a poor hasher costs speed, not correctness.
+type Hasher[K comparable] func(key K) uint32
+
+// HashString is the FNV-1a hash Cache uses for its keys. Wrap it to hash
+// string-based key types: func(k MyKey) uint32 { return HashString(string(k)) }.
+func HashString(key string) uint32 {
+	var h uint32
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+	return h
+}
+
+// TypedCache is Cache with keys of type K and values of type V. Values are
+// stored as V rather than interface{}, so Get needs no type assertion and
+// Set does not box non-pointer values into a separate heap allocation.
+//
+// It keeps the shards, hash chains and expiry index of Cache, and the
+// arbitrary eviction of SetMaxEntries, but not TinyLFU, cost accounting or
+// eviction policies.
+type TypedCache[K comparable, V any] struct {
+	shards [numShards]*typedShard[K, V]
+	ttl    time.Duration
+	hash   Hasher[K]
+	cancel context.CancelFunc
+	exited chan struct{}
+}
+
+// typedShard is a partition of a TypedCache, laid out as shard.
+type typedShard[K comparable, V any] struct {
+	mu      sync.RWMutex
+	items   map[uint32]*typedItem[K, V]
+	buckets map[int64]*typedItem[K, V]
+	tick    int64
+	swept   int64
+	count   int
+	peak    int
+
+	maxEntries int
+}
+
+// typedItem is an entry of a TypedCache, laid out as Item.
+type typedItem[K comparable, V any] struct {
+	key     K
+	hash    uint32
+	value   V
+	expires int64
+	next    *typedItem[K, V]
+
+	expPrev, expNext *typedItem[K, V]
+}
+
+// NewTyped creates a TypedCache whose keys are hashed by hash, with the
+// default TTL given, if any, as for New. A nil hash uses maphash.Comparable
+// with a seed of its own, which works for any comparable K; HashString is
+// faster for strings.
+// Call Close to stop the cleanup goroutine.
+func NewTyped[K comparable, V any](hash Hasher[K], ttl ...time.Duration) *TypedCache[K, V] {
+	return NewTypedWithContext[K, V](context.Background(), hash, ttl...)
+}
+
+// NewTypedWithContext is like NewTyped, but the cleanup goroutine also stops
+// when ctx is done.
+func NewTypedWithContext[K comparable, V any](ctx context.Context, hash Hasher[K], ttl ...time.Duration) *TypedCache[K, V] {
+	if hash == nil {
+		seed := maphash.MakeSeed()
+		hash = func(key K) uint32 {
+			h := maphash.Comparable(seed, key)
+			return uint32(h ^ h>>32)
+		}
+	}
+	c := &TypedCache[K, V]{ttl: DefaultExpiration, hash: hash}
+	if len(ttl) > 0 {
+		c.ttl = ttl[0]
+	}
+	var tick, now int64
+	if c.ttl > 0 {
+		tick = int64(c.ttl / 2)
+		now = time.Now().UnixNano()
+	}
+	for i := range c.shards {
+		c.shards[i] = &typedShard[K, V]{
+			items:   make(map[uint32]*typedItem[K, V]),
+			buckets: make(map[int64]*typedItem[K, V]),
+			tick:    tick,
+		}
+		if tick > 0 {
+			c.shards[i].swept = now / tick
+		}
+	}
+	if c.ttl > 0 {
+		ctx, c.cancel = context.WithCancel(ctx)
+		c.exited = make(chan struct{})
+		go c.cleanup(ctx)
+	}
+	return c
+}
+
+// Close stops the cleanup goroutine and waits for it to return, as
+// Cache.Close does.
+func (c *TypedCache[K, V]) Close() error {
+	if c.cancel != nil {
+		c.cancel()
+		<-c.exited
+	}
+	return nil
+}
+
+func (c *TypedCache[K, V]) getShard(h uint32) *typedShard[K, V] {
+	return c.shards[h%numShards]
+}
+
+// Set stores value under key. ttl is interpreted as in Cache.Set.
+func (c *TypedCache[K, V]) Set(key K, value V, ttl time.Duration) {
+	var exp int64
+	if ttl == DefaultExpiration {
+		ttl = c.ttl
+	}
+	if ttl > 0 {
+		exp = time.Now().Add(ttl).UnixNano()
+	}
+
+	hashed := c.hash(key)
+	sh := c.getShard(hashed)
+
+	item := &typedItem[K, V]{key: key, hash: hashed, value: value, expires: exp}
+	sh.mu.Lock()
+	if sh.maxEntries > 0 && sh.lookup(hashed, key) == nil {
+		for sh.count >= sh.maxEntries {
+			sh.evict()
+		}
+	}
+	sh.store(hashed, item)
+	sh.mu.Unlock()
+}
+
+// Get returns the value stored under key. An expired item is removed and
+// reported as missing.
+func (c *TypedCache[K, V]) Get(key K) (V, bool) {
+	hashed := c.hash(key)
+	sh := c.getShard(hashed)
+
+	sh.mu.RLock()
+	item := sh.lookup(hashed, key)
+	sh.mu.RUnlock()
+
+	var zero V
+	if item == nil {
+		return zero, false
+	}
+	if item.expires > 0 && time.Now().UnixNano() > item.expires {
+		sh.mu.Lock()
+		if sh.lookup(hashed, key) == item {
+			sh.remove(hashed, key)
+		}
+		sh.mu.Unlock()
+		return zero, false
+	}
+	return item.value, true
+}
+
+// Delete removes key from the cache, if it is there.
+func (c *TypedCache[K, V]) Delete(key K) {
+	hashed := c.hash(key)
+	sh := c.getShard(hashed)
+
+	sh.mu.Lock()
+	sh.remove(hashed, key)
+	sh.mu.Unlock()
+}
+
+// SetMaxEntries bounds the cache to about n items, split evenly across
+// shards, evicting arbitrary items as Cache.SetMaxEntries does.
+// A value of n <= 0 removes the bound.
+func (c *TypedCache[K, V]) SetMaxEntries(n int) {
+	per := 0
+	if n > 0 {
+		per = (n + numShards - 1) / numShards
+	}
+	for _, sh := range c.shards {
+		sh.mu.Lock()
+		sh.maxEntries = per
+		for per > 0 && sh.count > per {
+			sh.evict()
+		}
+		sh.mu.Unlock()
+	}
+}
+
+// Len returns the number of items stored in the cache, including expired
+// items not yet removed.
+func (c *TypedCache[K, V]) Len() int {
+	n := 0
+	for _, sh := range c.shards {
+		sh.mu.RLock()
+		n += sh.count
+		sh.mu.RUnlock()
+	}
+	return n
+}
+
+// cleanup empties the elapsed expiry buckets every ttl/2, as Cache.cleanup does.
+func (c *TypedCache[K, V]) cleanup(ctx context.Context) {
+	defer close(c.exited)
+	tick := time.NewTicker(c.ttl / 2)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-tick.C:
+		case <-ctx.Done():
+			return
+		}
+		now := time.Now().UnixNano()
+		for _, sh := range c.shards {
+			for done := false; !done; {
+				sh.mu.Lock()
+				if done = sh.expire(now, sweepBatch); done {
+					sh.shrink()
+				}
+				sh.mu.Unlock()
+			}
+		}
+	}
+}
+
+// evict removes the item Go's randomized map iteration yields first.
+// The caller must hold sh.mu for writing.
+func (sh *typedShard[K, V]) evict() {
+	for h, item := range sh.items {
+		sh.remove(h, item.key)
+		return
+	}
+}
+
+// lookup returns the item stored under key, whose hash is h, or nil.
+// The caller must hold sh.mu.
+func (sh *typedShard[K, V]) lookup(h uint32, key K) *typedItem[K, V] {
+	for item := sh.items[h]; item != nil; item = item.next {
+		if item.key == key {
+			return item
+		}
+	}
+	return nil
+}
+
+// store links item into the chain for h and into the expiry index,
+// replacing the item with the same key if there is one.
+// The caller must hold sh.mu for writing.
+func (sh *typedShard[K, V]) store(h uint32, item *typedItem[K, V]) {
+	sh.index(item)
+	var prev *typedItem[K, V]
+	for cur := sh.items[h]; cur != nil; prev, cur = cur, cur.next {
+		if cur.key == item.key {
+			sh.unindex(cur)
+			item.next = cur.next
+			sh.link(h, prev, item)
+			return
+		}
+	}
+	item.next = sh.items[h]
+	sh.items[h] = item
+	sh.count++
+	if sh.count > sh.peak {
+		sh.peak = sh.count
+	}
+}
+
+// remove unlinks the item stored under key, if any.
+// The caller must hold sh.mu for writing.
+func (sh *typedShard[K, V]) remove(h uint32, key K) {
+	var prev *typedItem[K, V]
+	for cur := sh.items[h]; cur != nil; prev, cur = cur, cur.next {
+		if cur.key == key {
+			if prev == nil && cur.next == nil {
+				delete(sh.items, h)
+			} else {
+				sh.link(h, prev, cur.next)
+			}
+			sh.unindex(cur)
+			sh.count--
+			return
+		}
+	}
+}
+
+// link makes item follow prev in the chain for h, or head it if prev is nil.
+func (sh *typedShard[K, V]) link(h uint32, prev, item *typedItem[K, V]) {
+	if prev == nil {
+		sh.items[h] = item
+	} else {
+		prev.next = item
+	}
+}
+
+// indexed reports whether item belongs in the expiry index.
+func (sh *typedShard[K, V]) indexed(item *typedItem[K, V]) bool {
+	return sh.tick > 0 && item.expires > 0
+}
+
+// bucket returns the tick of the expiry bucket holding item.
+func (sh *typedShard[K, V]) bucket(item *typedItem[K, V]) int64 {
+	return max(item.expires/sh.tick, sh.swept)
+}
+
+// index pushes item onto its expiry bucket.
+func (sh *typedShard[K, V]) index(item *typedItem[K, V]) {
+	if !sh.indexed(item) {
+		return
+	}
+	t := sh.bucket(item)
+	if head := sh.buckets[t]; head != nil {
+		head.expPrev = item
+		item.expNext = head
+	}
+	sh.buckets[t] = item
+}
+
+// unindex removes item from its expiry bucket.
+func (sh *typedShard[K, V]) unindex(item *typedItem[K, V]) {
+	if !sh.indexed(item) {
+		return
+	}
+	if item.expPrev != nil {
+		item.expPrev.expNext = item.expNext
+	} else if t := sh.bucket(item); item.expNext != nil {
+		sh.buckets[t] = item.expNext
+	} else {
+		delete(sh.buckets, t)
+	}
+	if item.expNext != nil {
+		item.expNext.expPrev = item.expPrev
+	}
+	item.expPrev, item.expNext = nil, nil
+}
+
+// expire removes the items in buckets whose tick has fully elapsed by now,
+// stopping after limit items. It reports whether every such bucket is empty.
+// The caller must hold sh.mu for writing.
+func (sh *typedShard[K, V]) expire(now int64, limit int) bool {
+	for end := now / sh.tick; sh.swept < end; sh.swept++ {
+		for item := sh.buckets[sh.swept]; item != nil; item = sh.buckets[sh.swept] {
+			if limit == 0 {
+				return false
+			}
+			sh.remove(item.hash, item.key)
+			limit--
+		}
+	}
+	return true
+}
+
+// shrink rebuilds the shard's maps once they hold under a quarter of their
+// peak item count, as shard.shrink does.
+// The caller must hold sh.mu for writing.
+func (sh *typedShard[K, V]) shrink() {
+	if sh.peak < shrinkMin || sh.count >= sh.peak/4 {
+		return
+	}
+	items := make(map[uint32]*typedItem[K, V], sh.count)
+	for h, item := range sh.items {
+		items[h] = item
+	}
+	buckets := make(map[int64]*typedItem[K, V], len(sh.buckets))
+	for t, item := range sh.buckets {
+		buckets[t] = item
+	}
+	sh.items, sh.buckets, sh.peak = items, buckets, sh.count
+}
@@ -0,0 +1,153 @@
+package v9
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"testing"
+	"time"
+
+	"benchmark-gocache/conformance"
+)
+
+// coord is a struct key, hashed by the default maphash hasher.
+type coord struct{ x, y int }
+
+func TestTypedCache_SetAndGet(t *testing.T) {
+	cache := NewTyped[coord, int](nil, 10*time.Minute)
+	defer cache.Close()
+
+	cache.Set(coord{1, 2}, 12, DefaultExpiration)
+	cache.Set(coord{2, 1}, 21, DefaultExpiration)
+	if v, found := cache.Get(coord{1, 2}); !found || v != 12 {
+		t.Errorf("Get({1 2}) = %v, %v; want 12, true", v, found)
+	}
+	cache.Set(coord{1, 2}, 120, DefaultExpiration)
+	if v, found := cache.Get(coord{1, 2}); !found || v != 120 {
+		t.Errorf("Get({1 2}) after overwrite = %v, %v; want 120, true", v, found)
+	}
+	cache.Delete(coord{2, 1})
+	if v, found := cache.Get(coord{2, 1}); found || v != 0 {
+		t.Errorf("Get({2 1}) after Delete = %v, %v; want 0, false", v, found)
+	}
+	if n := cache.Len(); n != 1 {
+		t.Errorf("Len() = %d, want 1", n)
+	}
+}
+
+// TestTypedCache_HashCollision verifies that keys are compared, not only
+// hashed, even with a hasher that maps every key to the same slot.
+func TestTypedCache_HashCollision(t *testing.T) {
+	cache := NewTyped[int, string](func(int) uint32 { return 7 }, 100*time.Millisecond)
+	defer cache.Close()
+
+	for i := 0; i < 10; i++ {
+		cache.Set(i, strconv.Itoa(i), NoExpiration)
+	}
+	cache.Set(10, "short", 50*time.Millisecond)
+	cache.Delete(3)
+	for i := 0; i < 10; i++ {
+		v, found := cache.Get(i)
+		if want := strconv.Itoa(i); found != (i != 3) || (found && v != want) {
+			t.Errorf("Get(%d) = %q, %v", i, v, found)
+		}
+	}
+	time.Sleep(300 * time.Millisecond)
+	if n := cache.Len(); n != 9 {
+		t.Errorf("Len() after cleanup = %d, want 9", n)
+	}
+}
+
+func TestTypedCache_MaxEntries(t *testing.T) {
+	cache := NewTyped[int, int](nil, 10*time.Minute)
+	defer cache.Close()
+	cache.SetMaxEntries(100)
+
+	for i := 0; i < 1000; i++ {
+		cache.Set(i, i, DefaultExpiration)
+	}
+	if n := cache.Len(); n > 100+numShards {
+		t.Errorf("Len() = %d, want at most %d", n, 100+numShards)
+	}
+	if _, found := cache.Get(999); !found {
+		t.Errorf("Expected the last key set to be present")
+	}
+
+	cache.SetMaxEntries(0)
+	for i := 0; i < 1000; i++ {
+		cache.Set(i, i, DefaultExpiration)
+	}
+	if n := cache.Len(); n != 1000 {
+		t.Errorf("Len() after removing the bound = %d, want 1000", n)
+	}
+}
+
+func TestTypedCache_Conformance(t *testing.T) {
+	conformance.Run(t, func(ttl time.Duration) conformance.Cache {
+		return NewTyped[string, any](HashString, ttl)
+	})
+}
+
+func TestTypedCache_Lifecycle(t *testing.T) {
+	conformance.RunLifecycle(t, func(ctx context.Context, ttl time.Duration) io.Closer {
+		return NewTypedWithContext[string, any](ctx, HashString, ttl)
+	})
+}
+
+// benchKeys is the keyspace of the Typed benchmarks.
+const benchKeys = 1 << 16
+
+// sink keeps the values read by the benchmarks alive.
+var sink int
+
+// BenchmarkTyped compares Set and Get of int values through Cache, which
+// boxes each value and needs a type assertion on Get, with TypedCache.
+func BenchmarkTyped(b *testing.B) {
+	keys := make([]string, benchKeys)
+	for i := range keys {
+		keys[i] = "key-" + strconv.Itoa(i)
+	}
+
+	b.Run("interface/Set", func(b *testing.B) {
+		cache := New(time.Minute)
+		defer cache.Close()
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			cache.Set(keys[i%benchKeys], i+benchKeys, DefaultExpiration)
+		}
+	})
+	b.Run("typed/Set", func(b *testing.B) {
+		cache := NewTyped[string, int](HashString, time.Minute)
+		defer cache.Close()
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			cache.Set(keys[i%benchKeys], i+benchKeys, DefaultExpiration)
+		}
+	})
+	b.Run("interface/Get", func(b *testing.B) {
+		cache := New(time.Minute)
+		defer cache.Close()
+		for i, key := range keys {
+			cache.Set(key, i+benchKeys, DefaultExpiration)
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			v, _ := cache.Get(keys[i%benchKeys])
+			sink += v.(int)
+		}
+	})
+	b.Run("typed/Get", func(b *testing.B) {
+		cache := NewTyped[string, int](HashString, time.Minute)
+		defer cache.Close()
+		for i, key := range keys {
+			cache.Set(key, i+benchKeys, DefaultExpiration)
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			v, _ := cache.Get(keys[i%benchKeys])
+			sink += v
+		}
+	})
+}
@@ -0,0 +1,113 @@
+package workload
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// OpKind is the kind of a cache operation.
+type OpKind uint8
+
+const (
+	OpGet OpKind = iota
+	OpSet
+	OpDelete
+)
+
+// OpKinds lists every operation kind, in the order used by Mix.
+var OpKinds = [...]OpKind{OpGet, OpSet, OpDelete}
+
+func (k OpKind) String() string {
+	switch k {
+	case OpGet:
+		return "get"
+	case OpSet:
+		return "set"
+	case OpDelete:
+		return "delete"
+	}
+	return "op(" + strconv.Itoa(int(k)) + ")"
+}
+
+// Op is a single pre-generated cache operation.
+type Op struct {
+	Kind OpKind
+	Key  string
+}
+
+// Mix is the relative weight of reads, writes and deletes in a workload.
+// Weights are usually percentages but only their ratio matters.
+type Mix struct {
+	Read, Write, Delete int
+}
+
+// ParseMix parses a read/write[/delete] ratio such as "90/10" or "80/15/5".
+func ParseMix(s string) (Mix, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) < 2 || len(parts) > 3 {
+		return Mix{}, fmt.Errorf("workload: mix %q must be read/write or read/write/delete", s)
+	}
+	var w [3]int
+	for i, p := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil || n < 0 {
+			return Mix{}, fmt.Errorf("workload: invalid weight %q in mix %q", p, s)
+		}
+		w[i] = n
+	}
+	m := Mix{Read: w[0], Write: w[1], Delete: w[2]}
+	if m.total() == 0 {
+		return Mix{}, fmt.Errorf("workload: mix %q has no operations", s)
+	}
+	return m, nil
+}
+
+// ParseMixes parses a comma-separated list of mixes.
+func ParseMixes(s string) ([]Mix, error) {
+	var mixes []Mix
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f == "" {
+			continue
+		}
+		m, err := ParseMix(f)
+		if err != nil {
+			return nil, err
+		}
+		mixes = append(mixes, m)
+	}
+	return mixes, nil
+}
+
+// String returns a name for m suitable for a benchmark name, such as
+// "r90-w10" or "r80-w15-d5".
+func (m Mix) String() string {
+	s := "r" + strconv.Itoa(m.Read) + "-w" + strconv.Itoa(m.Write)
+	if m.Delete > 0 {
+		s += "-d" + strconv.Itoa(m.Delete)
+	}
+	return s
+}
+
+func (m Mix) total() int { return m.Read + m.Write + m.Delete }
+
+// Ops pre-generates count operations drawn from m, with keys drawn from g.
+func Ops(m Mix, g Generator, count int, seed int64) []Op {
+	r := rand.New(rand.NewSource(seed))
+	total := m.total()
+
+	ops := make([]Op, count)
+	for i := range ops {
+		ops[i].Key = KeyName(g.Next())
+		switch n := r.Intn(total); {
+		case n < m.Read:
+			ops[i].Kind = OpGet
+		case n < m.Read+m.Write:
+			ops[i].Kind = OpSet
+		default:
+			ops[i].Kind = OpDelete
+		}
+	}
+	return ops
+}
@@ -0,0 +1,265 @@
+// Package workload generates key streams with realistic access distributions
+// for the cache benchmarks: uniform, Zipfian, hotspot, latest-biased and
+// scan-heavy.
+//
+// Streams are meant to be generated up front, outside the timed loop, with
+// Keys or Indexes, so the cost of drawing random numbers is never measured.
+package workload
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+)
+
+// Distribution names a key access distribution.
+type Distribution string
+
+const (
+	// Uniform picks every key with the same probability.
+	Uniform Distribution = "uniform"
+
+	// Zipf picks key i with probability proportional to 1/(i+1)^Skew,
+	// so a few low-numbered keys receive most of the traffic.
+	Zipf Distribution = "zipf"
+
+	// Hotspot sends HotOps of the traffic to the first HotKeys of the keyspace
+	// and spreads the rest uniformly over the remaining keys.
+	Hotspot Distribution = "hotspot"
+
+	// Latest is Zipfian over recency: the most recently inserted keys
+	// (the highest indexes) are the hottest.
+	Latest Distribution = "latest"
+
+	// Scan is Hotspot with the cold keys walked in order, over and over,
+	// instead of at random. Each pass of the scan touches every cold key once,
+	// which flushes the hot keys out of an LRU cache smaller than the scan.
+	Scan Distribution = "scan"
+)
+
+// Distributions lists every supported distribution.
+var Distributions = []Distribution{Uniform, Zipf, Hotspot, Latest, Scan}
+
+// ParseDistribution returns the Distribution named s.
+func ParseDistribution(s string) (Distribution, error) {
+	for _, d := range Distributions {
+		if string(d) == s {
+			return d, nil
+		}
+	}
+	return "", fmt.Errorf("workload: unknown distribution %q", s)
+}
+
+// Default parameters used when a Spec leaves them unset.
+const (
+	DefaultSkew    = 0.99 // YCSB's default Zipfian constant
+	DefaultHotKeys = 0.2  // 20% of the keys ...
+	DefaultHotOps  = 0.8  // ... receive 80% of the operations
+)
+
+// Spec describes a key stream.
+type Spec struct {
+	Distribution Distribution
+	Keys         int     // keyspace size
+	Skew         float64 // Zipf and Latest exponent; 0 means DefaultSkew
+	HotKeys      float64 // Hotspot and Scan fraction of hot keys; 0 means DefaultHotKeys
+	HotOps       float64 // Hotspot and Scan fraction of operations on hot keys; 0 means DefaultHotOps
+	Seed         int64
+}
+
+// String returns a short name for s suitable for a benchmark name,
+// such as "zipf-0.99" or "hotspot-20-80".
+func (s Spec) String() string {
+	s = s.withDefaults()
+	switch s.Distribution {
+	case Zipf, Latest:
+		return fmt.Sprintf("%s-%g", s.Distribution, s.Skew)
+	case Hotspot, Scan:
+		return fmt.Sprintf("%s-%g-%g", s.Distribution, s.HotKeys*100, s.HotOps*100)
+	}
+	return string(s.Distribution)
+}
+
+func (s Spec) withDefaults() Spec {
+	if s.Skew == 0 {
+		s.Skew = DefaultSkew
+	}
+	if s.HotKeys == 0 {
+		s.HotKeys = DefaultHotKeys
+	}
+	if s.HotOps == 0 {
+		s.HotOps = DefaultHotOps
+	}
+	return s
+}
+
+// Generator draws key indexes in [0, Keys).
+type Generator interface {
+	Next() uint64
+}
+
+// New builds the Generator described by s.
+func New(s Spec) (Generator, error) {
+	s = s.withDefaults()
+	if s.Keys < 1 {
+		return nil, fmt.Errorf("workload: keyspace must be positive, got %d", s.Keys)
+	}
+	r := rand.New(rand.NewSource(s.Seed))
+	n := uint64(s.Keys)
+
+	switch s.Distribution {
+	case Uniform:
+		return &uniform{r: r, n: n}, nil
+	case Zipf:
+		return newZipf(r, n, s.Skew)
+	case Latest:
+		z, err := newZipf(r, n, s.Skew)
+		if err != nil {
+			return nil, err
+		}
+		return &latest{z: z, n: n}, nil
+	case Hotspot, Scan:
+		if s.HotKeys <= 0 || s.HotKeys >= 1 || s.HotOps < 0 || s.HotOps > 1 {
+			return nil, fmt.Errorf("workload: invalid %s %g/%g", s.Distribution, s.HotKeys, s.HotOps)
+		}
+		hot := uint64(float64(n) * s.HotKeys)
+		if hot == 0 {
+			hot = 1
+		}
+		h := &hotspot{r: r, n: n, hot: hot, hotOps: s.HotOps}
+		if s.Distribution == Scan {
+			return &scan{hotspot: h, next: hot}, nil
+		}
+		return h, nil
+	}
+	return nil, fmt.Errorf("workload: unknown distribution %q", s.Distribution)
+}
+
+// Indexes draws count key indexes from g.
+func Indexes(g Generator, count int) []uint64 {
+	idx := make([]uint64, count)
+	for i := range idx {
+		idx[i] = g.Next()
+	}
+	return idx
+}
+
+// Keys draws count keys from g, named with KeyName.
+func Keys(g Generator, count int) []string {
+	keys := make([]string, count)
+	for i := range keys {
+		keys[i] = KeyName(g.Next())
+	}
+	return keys
+}
+
+// KeyName returns the cache key for index i.
+func KeyName(i uint64) string {
+	return "key-" + strconv.FormatUint(i, 10)
+}
+
+type uniform struct {
+	r *rand.Rand
+	n uint64
+}
+
+func (u *uniform) Next() uint64 { return uint64(u.r.Int63n(int64(u.n))) }
+
+type hotspot struct {
+	r      *rand.Rand
+	n, hot uint64
+	hotOps float64
+}
+
+func (h *hotspot) Next() uint64 {
+	if h.hot == h.n || h.r.Float64() < h.hotOps {
+		return uint64(h.r.Int63n(int64(h.hot)))
+	}
+	return h.hot + uint64(h.r.Int63n(int64(h.n-h.hot)))
+}
+
+// scan draws hot keys like its hotspot, and cold keys in order.
+type scan struct {
+	*hotspot
+	next uint64 // Next cold key of the scan
+}
+
+func (s *scan) Next() uint64 {
+	if s.hot == s.n || s.r.Float64() < s.hotOps {
+		return uint64(s.r.Int63n(int64(s.hot)))
+	}
+	i := s.next
+	if s.next++; s.next == s.n {
+		s.next = s.hot
+	}
+	return i
+}
+
+type latest struct {
+	z Generator
+	n uint64
+}
+
+func (l *latest) Next() uint64 { return l.n - 1 - l.z.Next() }
+
+// newZipf returns a Zipfian generator over [0, n).
+// Skews in (0, 1) use the algorithm from Gray et al., "Quickly Generating
+// Billion-Record Synthetic Databases" (the one YCSB uses); skews above 1
+// use math/rand's rejection sampler.
+func newZipf(r *rand.Rand, n uint64, skew float64) (Generator, error) {
+	switch {
+	case skew > 1:
+		return &stdZipf{z: rand.NewZipf(r, skew, 1, n-1)}, nil
+	case skew <= 0 || skew == 1:
+		return nil, fmt.Errorf("workload: zipf skew must be in (0, 1) or above 1, got %g", skew)
+	}
+
+	zetan := zeta(n, skew)
+	z := &gray{
+		r:     r,
+		n:     float64(n),
+		zetan: zetan,
+		alpha: 1 / (1 - skew),
+		eta:   (1 - math.Pow(2/float64(n), 1-skew)) / (1 - zeta(2, skew)/zetan),
+		half:  1 + math.Pow(0.5, skew),
+	}
+	return z, nil
+}
+
+// zeta returns the sum of 1/i^theta for i in [1, n].
+func zeta(n uint64, theta float64) float64 {
+	var sum float64
+	for i := uint64(1); i <= n; i++ {
+		sum += 1 / math.Pow(float64(i), theta)
+	}
+	return sum
+}
+
+type gray struct {
+	r                *rand.Rand
+	n, zetan         float64
+	alpha, eta, half float64
+}
+
+func (z *gray) Next() uint64 {
+	u := z.r.Float64()
+	uz := u * z.zetan
+	if uz < 1 {
+		return 0
+	}
+	if uz < z.half {
+		return 1
+	}
+	i := uint64(z.n * math.Pow(z.eta*u-z.eta+1, z.alpha))
+	if i >= uint64(z.n) {
+		i = uint64(z.n) - 1
+	}
+	return i
+}
+
+type stdZipf struct {
+	z *rand.Zipf
+}
+
+func (s *stdZipf) Next() uint64 { return s.z.Uint64() }
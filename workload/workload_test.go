@@ -0,0 +1,171 @@
+package workload
+
+import (
+	"testing"
+)
+
+const (
+	testKeys  = 1000
+	testDraws = 100000
+)
+
+// counts draws testDraws indexes from s and returns how often each index was drawn.
+func counts(t *testing.T, s Spec) []int {
+	t.Helper()
+	g, err := New(s)
+	if err != nil {
+		t.Fatalf("New(%v) error: %v", s, err)
+	}
+	c := make([]int, s.Keys)
+	for _, i := range Indexes(g, testDraws) {
+		if i >= uint64(s.Keys) {
+			t.Fatalf("%v drew index %d outside [0, %d)", s, i, s.Keys)
+		}
+		c[i]++
+	}
+	return c
+}
+
+func TestNew_InRange(t *testing.T) {
+	for _, d := range Distributions {
+		for _, skew := range []float64{0.5, 0.99, 1.2} {
+			counts(t, Spec{Distribution: d, Keys: testKeys, Skew: skew, Seed: 1})
+		}
+	}
+}
+
+func TestNew_Invalid(t *testing.T) {
+	tests := []Spec{
+		{Distribution: "gaussian", Keys: testKeys},
+		{Distribution: Uniform, Keys: 0},
+		{Distribution: Zipf, Keys: testKeys, Skew: 1},
+		{Distribution: Zipf, Keys: testKeys, Skew: -0.5},
+		{Distribution: Hotspot, Keys: testKeys, HotKeys: 1.5},
+	}
+	for _, s := range tests {
+		if _, err := New(s); err == nil {
+			t.Errorf("New(%+v) expected an error", s)
+		}
+	}
+}
+
+func TestNew_Deterministic(t *testing.T) {
+	for _, d := range Distributions {
+		s := Spec{Distribution: d, Keys: testKeys, Seed: 42}
+		g1, _ := New(s)
+		g2, _ := New(s)
+		a, b := Keys(g1, 1000), Keys(g2, 1000)
+		for i := range a {
+			if a[i] != b[i] {
+				t.Fatalf("%v: streams with the same seed differ at %d: %q != %q", s, i, a[i], b[i])
+			}
+		}
+	}
+}
+
+func TestZipf_Skewed(t *testing.T) {
+	c := counts(t, Spec{Distribution: Zipf, Keys: testKeys, Seed: 1})
+
+	// With skew 0.99 over 1000 keys, key 0 alone gets roughly 13% of draws,
+	// against 0.1% under a uniform distribution.
+	if share := float64(c[0]) / testDraws; share < 0.08 {
+		t.Errorf("key 0 share = %.3f, want a heavily skewed stream", share)
+	}
+	if c[0] <= c[1] || c[1] <= c[10] || c[10] <= c[500] {
+		t.Errorf("zipf counts not decreasing: c[0]=%d c[1]=%d c[10]=%d c[500]=%d", c[0], c[1], c[10], c[500])
+	}
+}
+
+func TestLatest_FavorsNewestKeys(t *testing.T) {
+	c := counts(t, Spec{Distribution: Latest, Keys: testKeys, Seed: 1})
+	if c[testKeys-1] <= c[0] {
+		t.Errorf("latest: newest key drawn %d times, oldest %d", c[testKeys-1], c[0])
+	}
+}
+
+func TestHotspot_Share(t *testing.T) {
+	c := counts(t, Spec{Distribution: Hotspot, Keys: testKeys, HotKeys: 0.1, HotOps: 0.9, Seed: 1})
+
+	hot := 0
+	for i := 0; i < testKeys/10; i++ {
+		hot += c[i]
+	}
+	if share := float64(hot) / testDraws; share < 0.88 || share > 0.92 {
+		t.Errorf("hot share = %.3f, want ~0.90", share)
+	}
+}
+
+func TestScan_Sequential(t *testing.T) {
+	g, err := New(Spec{Distribution: Scan, Keys: testKeys, HotKeys: 0.1, HotOps: 0.5, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hot, want := 0, uint64(testKeys/10)
+	for i := 0; i < testDraws; i++ {
+		k := g.Next()
+		if k < testKeys/10 {
+			hot++
+			continue
+		}
+		if k != want {
+			t.Fatalf("draw %d: cold key %d, want %d", i, k, want)
+		}
+		if want++; want == testKeys {
+			want = testKeys / 10
+		}
+	}
+	if share := float64(hot) / testDraws; share < 0.48 || share > 0.52 {
+		t.Errorf("hot share = %.3f, want ~0.50", share)
+	}
+}
+
+func TestSpec_String(t *testing.T) {
+	tests := map[string]Spec{
+		"uniform":       {Distribution: Uniform},
+		"zipf-0.99":     {Distribution: Zipf},
+		"latest-1.2":    {Distribution: Latest, Skew: 1.2},
+		"hotspot-20-80": {Distribution: Hotspot},
+		"scan-10-50":    {Distribution: Scan, HotKeys: 0.1, HotOps: 0.5},
+	}
+	for want, s := range tests {
+		if got := s.String(); got != want {
+			t.Errorf("%+v.String() = %q, want %q", s, got, want)
+		}
+	}
+}
+
+func TestParseMix(t *testing.T) {
+	tests := map[string]Mix{
+		"90/10":    {Read: 90, Write: 10},
+		"80/15/5":  {Read: 80, Write: 15, Delete: 5},
+		" 99 / 1 ": {Read: 99, Write: 1},
+	}
+	for s, want := range tests {
+		got, err := ParseMix(s)
+		if err != nil || got != want {
+			t.Errorf("ParseMix(%q) = %+v, %v, want %+v", s, got, err, want)
+		}
+	}
+
+	for _, s := range []string{"90", "1/2/3/4", "a/b", "-1/10", "0/0"} {
+		if _, err := ParseMix(s); err == nil {
+			t.Errorf("ParseMix(%q) expected an error", s)
+		}
+	}
+}
+
+func TestOps_Ratio(t *testing.T) {
+	g, _ := New(Spec{Distribution: Uniform, Keys: testKeys, Seed: 1})
+	ops := Ops(Mix{Read: 70, Write: 20, Delete: 10}, g, testDraws, 1)
+
+	var n [3]int
+	for _, op := range ops {
+		n[op.Kind]++
+	}
+	want := []float64{0.7, 0.2, 0.1}
+	for k, w := range want {
+		if share := float64(n[k]) / testDraws; share < w-0.01 || share > w+0.01 {
+			t.Errorf("%v share = %.3f, want ~%.2f", OpKind(k), share, w)
+		}
+	}
+}
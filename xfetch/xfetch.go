@@ -0,0 +1,69 @@
+// Package xfetch spreads out the expiries of the gocache versions, so that
+// keys set together do not all expire together and stampede the backend.
+//
+// It offers two opt-in mechanisms:
+//
+//   - Early expiration with XFetch, from Vattani, Chierichetti and Lowenstein,
+//     "Optimal Probabilistic Cache Stampede Prevention" (VLDB 2015). An item
+//     stores delta, the time its value took to compute. A Get at time now
+//     treats it as expired if now - delta*beta*ln(rand()) >= expires, with
+//     rand() uniform in (0, 1]. The closer the expiry and the costlier the
+//     value, the likelier one caller recomputes it ahead of the others, while
+//     they keep hitting. beta > 1 favours earlier recomputation.
+//   - Ttl jitter: every ttl applied by Set is shortened by a random fraction,
+//     up to the configured one, of its length.
+package xfetch
+
+import (
+	"math"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultBeta is the beta the XFetch paper recommends.
+const DefaultBeta = 1.0
+
+// Settings holds the early-expiration settings of a cache. The zero value
+// disables both mechanisms. Its methods are safe for concurrent use.
+type Settings struct {
+	beta   atomic.Uint64 // math.Float64bits of beta; 0 disables XFetch
+	jitter atomic.Uint64 // math.Float64bits of the jitter fraction; 0 disables it
+}
+
+// SetBeta sets the beta factor of XFetch; beta <= 0 turns XFetch off.
+func (s *Settings) SetBeta(beta float64) {
+	s.beta.Store(math.Float64bits(max(beta, 0)))
+}
+
+// SetJitter makes TTL shorten ttls by a random fraction of up to fraction,
+// which is clamped to [0, 1]. 0 turns jitter off.
+func (s *Settings) SetJitter(fraction float64) {
+	s.jitter.Store(math.Float64bits(min(max(fraction, 0), 1)))
+}
+
+// TTL returns ttl with jitter applied.
+func (s *Settings) TTL(ttl time.Duration) time.Duration {
+	j := math.Float64frombits(s.jitter.Load())
+	if j == 0 || ttl <= 0 {
+		return ttl
+	}
+	return ttl - time.Duration(j*rand.Float64()*float64(ttl))
+}
+
+// Early reports whether a Get at now, in Unix nanoseconds, should treat an
+// item expiring at expires, whose value took delta nanoseconds to compute,
+// as expired already. Items that never expire, or whose delta is unknown,
+// are never expired early.
+func (s *Settings) Early(now, expires, delta int64) bool {
+	if expires <= 0 || delta <= 0 {
+		return false
+	}
+	beta := math.Float64frombits(s.beta.Load())
+	if beta == 0 {
+		return false
+	}
+	// 1 - Float64() is in (0, 1], so the log is finite and <= 0.
+	gap := -float64(delta) * beta * math.Log(1-rand.Float64())
+	return float64(now)+gap >= float64(expires)
+}
@@ -0,0 +1,63 @@
+package xfetch
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSettings_Disabled(t *testing.T) {
+	var s Settings
+	if got := s.TTL(time.Minute); got != time.Minute {
+		t.Errorf("TTL() without jitter = %v, want 1m", got)
+	}
+	if s.Early(100, 101, 1e9) {
+		t.Error("Early() without a beta = true")
+	}
+}
+
+func TestSettings_TTL(t *testing.T) {
+	var s Settings
+	s.SetJitter(0.2)
+	lo, hi := time.Minute, time.Duration(0)
+	for i := 0; i < 10000; i++ {
+		got := s.TTL(time.Minute)
+		lo, hi = min(lo, got), max(hi, got)
+	}
+	if lo < 48*time.Second || hi > time.Minute {
+		t.Errorf("TTL(1m) with 20%% jitter ranged over [%v, %v], want within [48s, 1m]", lo, hi)
+	}
+	if hi-lo < 11*time.Second {
+		t.Errorf("TTL(1m) with 20%% jitter ranged over [%v, %v], want about 12s wide", lo, hi)
+	}
+	if got := s.TTL(-1); got != -1 {
+		t.Errorf("TTL(-1) = %v, want -1", got)
+	}
+}
+
+// TestSettings_Early checks the probability of an early expiration against
+// the closed form: P(-delta*beta*ln(u) >= gap) = exp(-gap/(delta*beta)).
+func TestSettings_Early(t *testing.T) {
+	var s Settings
+	s.SetBeta(DefaultBeta)
+	const now, delta, draws = 1e9, 1000, 100000
+	for _, gap := range []int64{0, 500, 1000, 3000} {
+		early := 0
+		for i := 0; i < draws; i++ {
+			if s.Early(now, now+gap, delta) {
+				early++
+			}
+		}
+		want := math.Exp(-float64(gap) / delta)
+		if got := float64(early) / draws; math.Abs(got-want) > 0.01 {
+			t.Errorf("P(early) %d ns before expiry = %.3f, want %.3f", gap, got, want)
+		}
+	}
+	if s.Early(0, 0, delta) || s.Early(0, 10, 0) {
+		t.Error("Early() of an item without expiry or delta = true")
+	}
+	s.SetBeta(0)
+	if s.Early(now, now+1, delta) {
+		t.Error("Early() after SetBeta(0) = true")
+	}
+}